@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/DaniruKun/tipfax/internal/config"
+	"github.com/DaniruKun/tipfax/internal/streamelements"
+)
+
+// runCheck is the check subcommand: a preflight that loads and validates the
+// configuration, checks the token, then connects to Astro and subscribes to
+// tips without opening any printer. It prints PASS or FAIL for each step,
+// stops at the first failure, and returns the exit status.
+func runCheck(configPath string) int {
+	report := func(step string, err error) bool {
+		if err != nil {
+			fmt.Printf("FAIL %s: %v\n", step, err)
+			return false
+		}
+		fmt.Printf("PASS %s\n", step)
+		return true
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if !report("config loaded", err) {
+		return 1
+	}
+	if !report("config valid", cfg.Validate()) {
+		return 1
+	}
+
+	token, err := cfg.LoadToken()
+	if err == nil {
+		err = checkToken(token)
+	}
+	if !report("token valid", err) {
+		return 1
+	}
+	if cfg.SeModerationJWTToken != "" && !report("moderation token valid", checkToken(cfg.SeModerationJWTToken)) {
+		return 1
+	}
+
+	// Only the connection is checked, so leave out everything that records or
+	// forwards tips.
+	cfg.TipLogPath, cfg.TipDBPath = "", ""
+	cfg.WebhookURL, cfg.DiscordWebhookURL, cfg.SlackWebhookURL = "", "", ""
+	cfg.TTS, cfg.DesktopNotifications = false, false
+
+	astro := streamelements.NewAstro(cfg, nil, newLogger(cfg))
+
+	if !report("connected", astro.Connect()) {
+		return 1
+	}
+	defer astro.Disconnect()
+
+	// SubscribeTips waits up to SUBSCRIBE_TIMEOUT for Astro's answer.
+	if !report("subscribed", astro.SubscribeTips(context.Background())) {
+		return 1
+	}
+
+	return 0
+}
+
+// checkToken reports whether token is a well-formed JWT that hasn't expired.
+func checkToken(token string) error {
+	if err := config.ValidateJWT(token); err != nil {
+		return err
+	}
+	if exp, ok := config.JWTExpiry(token); ok && time.Now().After(exp) {
+		return fmt.Errorf("expired at %s", exp.Local().Format(time.RFC3339))
+	}
+	return nil
+}
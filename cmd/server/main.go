@@ -1,52 +1,149 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/DaniruKun/tipfax/internal/config"
 	"github.com/DaniruKun/tipfax/internal/fax"
+	"github.com/DaniruKun/tipfax/internal/metrics"
 	"github.com/DaniruKun/tipfax/internal/streamelements"
 	"github.com/DaniruKun/tipfax/internal/web"
 	"github.com/securityguy/escpos"
 )
 
 func main() {
+	replayPath := flag.String("replay", "", "replay tips from a JSONL tip log instead of connecting to StreamElements")
+	replaySpeed := flag.Float64("replay-speed", 0, "replay tips at this multiple of their recorded pace, 0 for as fast as possible")
+	replayLoop := flag.Bool("replay-loop", false, "replay the tip log over and over until interrupted")
+	testPrint := flag.Bool("test-print", false, "print a sample receipt and exit")
+	calibrate := flag.Bool("calibrate", false, "print a column ruler, font sizes and a QR code to check PRINTER_COLUMNS, and exit")
+	configPath := flag.String("config", "", "read settings from this YAML file; environment variables override it")
+	flag.Parse()
+
+	// "check" is a preflight: validate the config and token, and subscribe
+	// once, without touching the printer.
+	if flag.Arg(0) == "check" {
+		os.Exit(runCheck(*configPath))
+	}
+
 	fmt.Println("Starting TipFax Server...")
 
-	cfg := config.New()
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load configuration:\n%v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	logger := newLogger(cfg)
+	slog.SetDefault(logger)
 
 	// Test printer connection
-	printer, err := fax.NewPrinter(cfg.DevicePath)
-	if err != nil {
-		log.Printf("Warning: Failed to create printer: %v", err)
-		log.Println("Continuing without printer...")
+	var printer fax.Printer
+	if cfg.DryRun {
+		log.Println("Dry run: receipts will be echoed to stdout instead of printed")
+		printer = fax.NewConsolePrinter(os.Stdout)
+	} else if device, err := openPrinter(cfg, cfg.DevicePath); err != nil {
+		if cfg.RequirePrinter {
+			log.Fatalf("Failed to open printer at %s: %v", cfg.DevicePath, err)
+		}
+		log.Printf("Warning: Failed to open printer at %s: %v", cfg.DevicePath, err)
+		log.Println("Continuing without printer, tips will only be logged")
 	} else {
-		printer.SetConfig(escpos.ConfigEpsonTMT20II)
-		printer.Write("TipFax Server Started!")
-		printer.LineFeed()
-		printer.PrintAndCut()
-		log.Println("Printer test successful")
+		logPrinterInit(logger, cfg, cfg.DevicePath)
+		if !*testPrint && !*calibrate {
+			device.Write("TipFax Server Started!")
+			device.LineFeed()
+			device.PrintAndCut()
+			log.Println("Printer test successful")
+		}
+		printer = device
+	}
+
+	astro := streamelements.NewAstro(cfg, printer, logger)
+
+	for name, path := range cfg.Printers {
+		if cfg.DryRun {
+			astro.AddPrinter(name, fax.NewConsolePrinter(os.Stdout))
+			continue
+		}
+		device, err := openPrinter(cfg, path)
+		if err != nil {
+			if cfg.RequirePrinter {
+				log.Fatalf("Failed to open printer %s at %s: %v", name, path, err)
+			}
+			log.Printf("Warning: Failed to open printer %s at %s: %v", name, path, err)
+			continue
+		}
+		logPrinterInit(logger, cfg, path)
+		astro.AddPrinter(name, device)
+	}
+
+	if *testPrint {
+		if err := astro.TestPrint(); err != nil {
+			log.Fatalf("Test print failed: %v", err)
+		}
+		log.Println("Test print succeeded")
+		return
 	}
 
-	// Connect to StreamElements Astro
-	astro := streamelements.NewAstro(cfg, printer)
-	if err := astro.Connect(); err != nil {
+	if *calibrate {
+		if err := astro.PrintCalibration(); err != nil {
+			log.Fatalf("Calibration print failed: %v", err)
+		}
+		log.Println("Calibration print succeeded")
+		return
+	}
+
+	if *replayPath != "" {
+		replayCtx, replayCancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		err := astro.Replay(replayCtx, *replayPath, streamelements.ReplayOptions{Speed: *replaySpeed, Loop: *replayLoop})
+		replayCancel()
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Fatalf("Failed to replay %s: %v", *replayPath, err)
+		}
+		astro.FlushPrintQueue()
+		log.Println("Replay finished")
+		return
+	}
+
+	// Connect to StreamElements Astro, retrying in case the network isn't up
+	// yet. Ctrl+C still works while waiting.
+	connectCtx, connectCancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	err = astro.ConnectWithRetry(connectCtx, cfg.MaxReconnectAttempts)
+	connectCancel()
+	if err != nil {
 		log.Fatalf("Failed to connect to StreamElements Astro: %v", err)
 	}
-	defer astro.Disconnect()
 
-	if err := astro.SubscribeTips(); err != nil {
+	// Each subscription waits up to SUBSCRIBE_TIMEOUT for Astro's answer.
+	if err := astro.SubscribeTips(context.Background()); err != nil {
 		log.Fatalf("Failed to subscribe to tips: %v", err)
 	}
-	defer astro.UnsubscribeTips()
+
+	if err := astro.SubscribeModeration(context.Background()); errors.Is(err, streamelements.ErrAuth) {
+		log.Printf("Warning: Astro rejected the token for tip moderation: %v", err)
+		log.Println("Continuing with tips only. SE_MODERATION_JWT_TOKEN sets a separate token allowed to read moderation decisions")
+	} else if err != nil {
+		log.Printf("Warning: Failed to subscribe to tip moderation: %v", err)
+	}
 
 	// Start HTTP server
 	http.HandleFunc("/", web.StatusHandler(cfg, cfg.DevicePath))
+	http.HandleFunc("/tips/stream", web.TipStreamHandler(astro, cfg.OverlayAllowOrigin))
+	http.HandleFunc("/tips/recent", web.RecentTipsHandler(astro, cfg.OverlayAllowOrigin))
+	http.HandleFunc("/tips/totals", web.TipTotalsHandler(astro, cfg.OverlayAllowOrigin))
 	go func() {
 		log.Printf("Starting HTTP server on http://localhost%s", cfg.ServerPort)
 		if err := http.ListenAndServe(cfg.ServerPort, nil); err != nil {
@@ -54,11 +151,74 @@ func main() {
 		}
 	}()
 
-	// Start listening for messages in a goroutine
+	// Optional ops listeners. Endpoints configured with the same address share
+	// one server.
+	opsMuxes := make(map[string]*http.ServeMux)
+	opsMux := func(addr string) *http.ServeMux {
+		if mux, ok := opsMuxes[addr]; ok {
+			return mux
+		}
+		mux := http.NewServeMux()
+		opsMuxes[addr] = mux
+		return mux
+	}
+	if cfg.HealthAddr != "" {
+		mux := opsMux(cfg.HealthAddr)
+		mux.HandleFunc("/healthz", web.HealthHandler(astro, cfg.HealthMaxSilence))
+		mux.HandleFunc("/readyz", web.ReadyHandler(astro))
+		mux.HandleFunc("/stats", web.StatsHandler(astro))
+		mux.HandleFunc("/pause", web.PauseHandler(astro, true))
+		mux.HandleFunc("/resume", web.PauseHandler(astro, false))
+	}
+	if cfg.MetricsAddr != "" {
+		opsMux(cfg.MetricsAddr).Handle(cfg.MetricsPath, metrics.Handler())
+	}
+	for addr, mux := range opsMuxes {
+		go func() {
+			log.Printf("Starting ops server on %s", addr)
+			if err := http.ListenAndServe(addr, mux); err != nil {
+				log.Printf("Ops server error: %v", err)
+			}
+		}()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Start listening for messages in a goroutine. Cancelling ctx makes it
+	// unsubscribe and disconnect before returning.
+	listenDone := make(chan struct{})
+	var listenErr error
 	go func() {
+		defer close(listenDone)
 		log.Println("Starting to listen for tip messages...")
-		if err := astro.Listen(); err != nil {
-			log.Printf("Error listening for messages: %v", err)
+		listenErr = astro.ListenWithReconnect(ctx)
+		if listenErr != nil && listenErr != context.Canceled {
+			log.Printf("Error listening for messages: %v", listenErr)
+		}
+	}()
+
+	go astro.RunSummarySchedule(ctx)
+	go astro.RunHeartbeat(ctx)
+	go astro.RunFrameStatsLog(ctx)
+
+	// SIGUSR1 prints a tip summary on demand.
+	summaryChan := make(chan os.Signal, 1)
+	signal.Notify(summaryChan, syscall.SIGUSR1)
+	go func() {
+		for range summaryChan {
+			if err := astro.PrintSummary(); err != nil {
+				log.Printf("Failed to print tip summary: %v", err)
+			}
+		}
+	}()
+
+	// SIGUSR2 toggles compact receipts, e.g. when running low on paper.
+	compactChan := make(chan os.Signal, 1)
+	signal.Notify(compactChan, syscall.SIGUSR2)
+	go func() {
+		for range compactChan {
+			astro.SetCompactReceipts(!astro.CompactReceipts())
 		}
 	}()
 
@@ -69,7 +229,79 @@ func main() {
 	log.Println("TipFax Server is running. Press Ctrl+C to stop.")
 	log.Printf("Web interface available at: http://localhost%s", cfg.ServerPort)
 
-	// Wait for shutdown signal
-	<-sigChan
-	log.Println("Shutting down TipFax Server...")
+	// Wait for a shutdown signal, or for the listener to give up reconnecting
+	gaveUp := false
+	select {
+	case <-sigChan:
+		log.Println("Shutting down TipFax Server...")
+		cancel()
+
+		select {
+		case <-listenDone:
+		case <-time.After(3 * time.Second):
+			log.Println("Timed out waiting for Astro connection to close")
+		}
+	case <-listenDone:
+		log.Println("Listener stopped, shutting down TipFax Server...")
+		if errors.Is(listenErr, streamelements.ErrReconnectLimit) || errors.Is(listenErr, streamelements.ErrAuth) {
+			gaveUp = true
+		}
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	if err := astro.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error during shutdown: %v", err)
+	}
+	shutdownCancel()
+
+	if gaveUp {
+		os.Exit(1)
+	}
+}
+
+// openPrinter opens the printer at path over PRINTER_TRANSPORT and switches
+// it to CODE_PAGE, if set.
+func openPrinter(cfg *config.Config, path string) (*fax.Device, error) {
+	init := cfg.PrinterInitBytes()
+	cp, hasCodePage := fax.LookupCodePage(cfg.CodePage)
+	if hasCodePage {
+		init = append(init, cp.Select()...)
+	}
+
+	var device *fax.Device
+	var err error
+	if cfg.PrinterTransport == "tcp" {
+		device, err = fax.OpenNetworkDevice(path, cfg.PrinterConnectTimeout, cfg.PrinterKeepAlive, escpos.ConfigEpsonTMT20II, init)
+	} else {
+		device, err = fax.OpenDevice(path, escpos.ConfigEpsonTMT20II, init)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if hasCodePage {
+		device.SetCodePage(cp)
+	}
+	return device, nil
+}
+
+// logPrinterInit logs the PRINTER_INIT commands sent to the printer at path.
+func logPrinterInit(logger *slog.Logger, cfg *config.Config, path string) {
+	for _, cmd := range cfg.PrinterInit {
+		logger.Debug("sent printer init command", "printer", path, "command", cmd.Text, "bytes", fmt.Sprintf("% x", cmd.Bytes))
+	}
+}
+
+// newLogger builds the process logger from LOG_LEVEL and LOG_FORMAT.
+func newLogger(cfg *config.Config) *slog.Logger {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		log.Printf("Warning: Invalid LOG_LEVEL %q, using info", cfg.LogLevel)
+		level = slog.LevelInfo
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	if cfg.LogFormat == "json" {
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts))
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, opts))
 }
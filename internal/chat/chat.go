@@ -0,0 +1,145 @@
+// Package chat posts tip announcements to chat platforms through their
+// incoming webhooks.
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	queueSize   = 100
+	maxAttempts = 5
+
+	// maxRetryAfter caps how long a Retry-After header can make a Poster wait.
+	maxRetryAfter = 5 * time.Minute
+)
+
+// Tip is what an announcement shows, already formatted for display.
+type Tip struct {
+	Username string
+	Amount   string // e.g. "$5.00"
+	Message  string
+	Provider string
+	Time     time.Time
+	TipID    string // only logged, never posted
+}
+
+// Platform turns a tip into the JSON body of a platform's incoming webhook.
+type Platform interface {
+	Name() string
+	Payload(t Tip) any
+}
+
+// Poster posts tips to one webhook URL from a background goroutine, one at a
+// time, so slow or rate-limited platforms never block the caller.
+type Poster struct {
+	url      string
+	platform Platform
+	client   *http.Client
+	queue    chan Tip
+	logger   *slog.Logger
+}
+
+// New creates a Poster for platform and starts its delivery goroutine.
+// Dropped and failed posts are logged to logger.
+func New(url string, platform Platform, timeout time.Duration, logger *slog.Logger) *Poster {
+	p := &Poster{
+		url:      url,
+		platform: platform,
+		client:   &http.Client{Timeout: timeout},
+		queue:    make(chan Tip, queueSize),
+		logger:   logger.With("platform", platform.Name()),
+	}
+	go p.run()
+	return p
+}
+
+// Send queues t for posting. If the queue is full the tip is dropped and
+// logged rather than blocking.
+func (p *Poster) Send(t Tip) {
+	select {
+	case p.queue <- t:
+	default:
+		p.logger.Warn("chat queue full, dropping tip", "tip_id", t.TipID)
+	}
+}
+
+func (p *Poster) run() {
+	for t := range p.queue {
+		if err := p.deliver(t); err != nil {
+			p.logger.Warn("failed to post tip to chat", "tip_id", t.TipID, "error", err)
+		}
+	}
+}
+
+// deliver posts t, waiting out 429 responses for as long as their
+// Retry-After header asks and retrying network errors and 5xx responses.
+func (p *Poster) deliver(t Tip) error {
+	body, err := json.Marshal(p.platform.Payload(t))
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		wait, err := p.post(body)
+		if err == nil || wait < 0 {
+			return err
+		}
+		if attempt == maxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", maxAttempts, err)
+		}
+		if wait == 0 {
+			wait = time.Duration(attempt) * time.Second
+		}
+		time.Sleep(wait)
+	}
+}
+
+// post sends body once. On failure it returns how long to wait before
+// retrying: the server's Retry-After, 0 for the default backoff, or a
+// negative duration if retrying won't help.
+func (p *Poster) post(body []byte) (time.Duration, error) {
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return -1, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return retryAfter(resp.Header.Get("Retry-After")), fmt.Errorf("%s rate limited the post", p.platform.Name())
+	case resp.StatusCode >= 500:
+		return 0, fmt.Errorf("%s returned %s", p.platform.Name(), resp.Status)
+	case resp.StatusCode >= 300:
+		return -1, fmt.Errorf("%s returned %s", p.platform.Name(), resp.Status)
+	}
+	return 0, nil
+}
+
+// retryAfter parses a Retry-After header given in seconds, which may be
+// fractional, or as an HTTP date. It returns 0 if the header is missing or
+// invalid.
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	var d time.Duration
+	if secs, err := strconv.ParseFloat(header, 64); err == nil {
+		d = time.Duration(secs * float64(time.Second))
+	} else if at, err := http.ParseTime(header); err == nil {
+		d = time.Until(at)
+	}
+	return min(max(d, 0), maxRetryAfter)
+}
@@ -0,0 +1,110 @@
+package chat
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDeliver(t *testing.T) {
+	tests := []struct {
+		name         string
+		responses    []int // status codes returned in turn, the last one repeated
+		retryAfter   string
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"ok", []int{http.StatusNoContent}, "", 1, false},
+		{"rate limited once", []int{http.StatusTooManyRequests, http.StatusOK}, "0.01", 2, false},
+		{"rate limited throughout", []int{http.StatusTooManyRequests}, "0.01", maxAttempts, true},
+		{"server error once", []int{http.StatusBadGateway, http.StatusOK}, "", 2, false},
+		{"bad request", []int{http.StatusBadRequest}, "", 1, true},
+		{"not found", []int{http.StatusNotFound}, "", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu     sync.Mutex
+				bodies []map[string]any
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]any
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("decode posted body: %v", err)
+				}
+				if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+					t.Errorf("Content-Type = %q, want application/json", ct)
+				}
+
+				mu.Lock()
+				bodies = append(bodies, body)
+				status := tt.responses[min(len(bodies), len(tt.responses))-1]
+				mu.Unlock()
+
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(status)
+			}))
+			defer srv.Close()
+
+			p := &Poster{url: srv.URL, platform: Discord{}, client: srv.Client()}
+			err := p.deliver(Tip{Username: "Alice", Amount: "$5.00", Provider: "paypal"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("deliver error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(bodies) != tt.wantAttempts {
+				t.Errorf("posted %d times, want %d", len(bodies), tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"2", 2 * time.Second},
+		{"0.5", 500 * time.Millisecond},
+		{"-3", 0},
+		{"soon", 0},
+		{"86400", maxRetryAfter},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.header); got != tt.want {
+			t.Errorf("retryAfter(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+// TestSend checks the background goroutine delivers queued tips.
+func TestSend(t *testing.T) {
+	got := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text string `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		got <- body.Text
+	}))
+	defer srv.Close()
+
+	New(srv.URL, Slack{}, time.Second, slog.New(slog.NewTextHandler(io.Discard, nil))).Send(Tip{Username: "Alice", Amount: "$5.00", Provider: "paypal"})
+
+	select {
+	case text := <-got:
+		if want := "Tip from Alice: $5.00"; text != want {
+			t.Errorf("posted text %q, want %q", text, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("tip was never posted")
+	}
+}
@@ -0,0 +1,64 @@
+package chat
+
+import (
+	"strings"
+	"time"
+)
+
+// Discord formats tips as a Discord embed.
+type Discord struct{}
+
+func (Discord) Name() string { return "Discord" }
+
+// discordColor is the embed's accent color.
+const discordColor = 0x8a5cf6
+
+func (Discord) Payload(t Tip) any {
+	embed := map[string]any{
+		"title": "Tip from " + t.Username,
+		"color": discordColor,
+		"fields": []map[string]any{
+			{"name": "Amount", "value": t.Amount, "inline": true},
+			{"name": "Provider", "value": t.Provider, "inline": true},
+		},
+	}
+	if t.Message != "" {
+		embed["description"] = t.Message
+	}
+	if !t.Time.IsZero() {
+		embed["timestamp"] = t.Time.UTC().Format(time.RFC3339)
+	}
+	return map[string]any{
+		"embeds": []any{embed},
+		// Donor messages must never ping anyone.
+		"allowed_mentions": map[string]any{"parse": []string{}},
+	}
+}
+
+// Slack formats tips as a Slack message with a section block.
+type Slack struct{}
+
+func (Slack) Name() string { return "Slack" }
+
+func (Slack) Payload(t Tip) any {
+	summary := slackEscape("Tip from " + t.Username + ": " + t.Amount)
+	text := "*Tip from " + slackEscape(t.Username) + "*: " + slackEscape(t.Amount) + " via " + slackEscape(t.Provider)
+	if t.Message != "" {
+		text += "\n>" + strings.ReplaceAll(slackEscape(t.Message), "\n", "\n>")
+	}
+	return map[string]any{
+		"text": summary, // shown in notifications
+		"blocks": []any{
+			map[string]any{
+				"type": "section",
+				"text": map[string]any{"type": "mrkdwn", "text": text},
+			},
+		},
+	}
+}
+
+// slackEscape escapes the characters Slack treats as markup, which also
+// keeps donors from sending <!channel> mentions.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
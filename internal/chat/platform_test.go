@@ -0,0 +1,65 @@
+package chat
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testTip = Tip{
+	Username: "Alice",
+	Amount:   "$5.00",
+	Message:  "hi @everyone\nsecond line",
+	Provider: "paypal",
+	Time:     time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+}
+
+func TestPayloads(t *testing.T) {
+	tests := []struct {
+		name     string
+		platform Platform
+		tip      Tip
+		want     []string // substrings of the JSON payload
+		notWant  []string
+	}{
+		{"discord", Discord{}, testTip, []string{
+			`"title":"Tip from Alice"`,
+			`"name":"Amount","value":"$5.00"`,
+			`"name":"Provider","value":"paypal"`,
+			`"description":"hi @everyone\nsecond line"`,
+			`"timestamp":"2025-03-01T12:00:00Z"`,
+			`"allowed_mentions":{"parse":[]}`,
+		}, nil},
+		{"discord without message or time", Discord{}, Tip{Username: "Bob", Amount: "€1,00", Provider: "stripe"},
+			[]string{`"title":"Tip from Bob"`}, []string{`"description"`, `"timestamp"`}},
+		{"slack", Slack{}, testTip, []string{
+			`"text":"Tip from Alice: $5.00"`,
+			`"text":"*Tip from Alice*: $5.00 via paypal\n>hi @everyone\n>second line"`,
+		}, nil},
+		{"slack escapes markup", Slack{}, Tip{Username: "<!channel>", Amount: "$5.00", Message: "a & b", Provider: "paypal"},
+			[]string{`&lt;!channel&gt;`, `a &amp; b`}, []string{`<!channel>`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Unescaped, so Slack's own &lt; escapes are visible as such.
+			var b strings.Builder
+			enc := json.NewEncoder(&b)
+			enc.SetEscapeHTML(false)
+			if err := enc.Encode(tt.platform.Payload(tt.tip)); err != nil {
+				t.Fatalf("marshal payload: %v", err)
+			}
+			payload := b.String()
+			for _, s := range tt.want {
+				if !strings.Contains(payload, s) {
+					t.Errorf("payload %s doesn't contain %s", payload, s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(payload, s) {
+					t.Errorf("payload %s contains %s", payload, s)
+				}
+			}
+		})
+	}
+}
@@ -1,23 +1,574 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
-
-	env "github.com/caarlos0/env/v11"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	SeJWTToken string `env:"SE_JWT_TOKEN,required"`
-	DevicePath string `env:"DEVICE_PATH" envDefault:"/dev/usb/lp0"` // printer device path
-	ServerPort string `env:"SERVER_PORT" envDefault:":8082"`        // server port
+	SeJWTToken string `env:"SE_JWT_TOKEN"`
+	// SeJWTTokenFile holds the token instead of SE_JWT_TOKEN. It is re-read on
+	// every reconnect, so a rotated token can be dropped in without a restart.
+	SeJWTTokenFile string `env:"SE_JWT_TOKEN_FILE"`
+	// SeModerationJWTToken subscribes the SE_JWT_TOKEN channel to tip
+	// moderation, for accounts where that needs another token. Empty uses
+	// the tips token.
+	SeModerationJWTToken string `env:"SE_MODERATION_JWT_TOKEN"`
+
+	// Tips from more channels can be received over the same connection, each
+	// subscribed with its own token, e.g. second=eyJ... The SE_JWT_TOKEN
+	// channel is named ChannelName. Received tips are tagged with the name.
+	ChannelName   string            `env:"CHANNEL_NAME" envDefault:"default"`
+	ChannelTokens map[string]string `env:"CHANNEL_TOKENS" envKeyValSeparator:"="`
+	PrintChannel  bool              `env:"PRINT_CHANNEL" envDefault:"false"` // print the channel name at the top of receipts
+
+	DevicePath     string `env:"DEVICE_PATH" envDefault:"/dev/usb/lp0"` // printer device path
+	ServerPort     string `env:"SERVER_PORT" envDefault:":8082"`        // server port
+	DryRun         bool   `env:"DRY_RUN" envDefault:"false"`            // echo receipts to stdout instead of printing
+	RequirePrinter bool   `env:"REQUIRE_PRINTER" envDefault:"false"`    // exit at startup if a printer can't be opened
+	LogLevel       string `env:"LOG_LEVEL" envDefault:"info"`           // debug, info, warn or error
+	LogFormat      string `env:"LOG_FORMAT" envDefault:"text"`          // text for humans, json for log aggregation
+
+	// PrinterTransport is how DEVICE_PATH and PRINTERS paths are reached: usb
+	// and serial open a device file, tcp dials a host:port such as a Wi-Fi
+	// printer's raw port 9100.
+	PrinterTransport      string        `env:"PRINTER_TRANSPORT" envDefault:"usb"`
+	PrinterConnectTimeout time.Duration `env:"PRINTER_CONNECT_TIMEOUT" envDefault:"5s"` // tcp only
+	PrinterKeepAlive      time.Duration `env:"PRINTER_KEEPALIVE" envDefault:"30s"`      // tcp only, 0 for the OS default
+
+	// DebugRawMessages logs every frame from Astro in full, with tokens
+	// redacted, at info level.
+	DebugRawMessages bool `env:"DEBUG_RAW_MESSAGES" envDefault:"false"`
+
+	// OverlayAllowOrigin is sent as Access-Control-Allow-Origin by the /tips
+	// endpoints so browser overlays can read them. Empty sends no CORS headers.
+	OverlayAllowOrigin string `env:"OVERLAY_ALLOW_ORIGIN" envDefault:"*"`
+
+	PrinterColumns  int           `env:"PRINTER_COLUMNS" envDefault:"32"`      // characters per line: 32 for 58mm, 48 for 80mm paper
+	PrintRetries    int           `env:"PRINT_RETRIES" envDefault:"3"`         // extra attempts for a receipt that failed to print
+	PrintRetryDelay time.Duration `env:"PRINT_RETRY_DELAY" envDefault:"500ms"` // wait between print attempts
+	// Tips that can't be printed are queued, oldest dropped first when the
+	// queue is full, and retried every PrintQueueRetryInterval.
+	PrintQueueSize          int           `env:"PRINT_QUEUE_SIZE" envDefault:"100"`
+	PrintQueueRetryInterval time.Duration `env:"PRINT_QUEUE_RETRY_INTERVAL" envDefault:"10s"`
+
+	// PrinterStatusInterval is how often the printer is asked for paper-out,
+	// cover-open and cutter errors. 0 turns polling off.
+	PrinterStatusInterval time.Duration `env:"PRINTER_STATUS_INTERVAL" envDefault:"15s"`
+
+	// PrinterInit is sent to every printer after opening it, and again after
+	// reopening it, before any receipt, e.g. codepage=16;density=2.
+	PrinterInit []PrinterCommand `env:"PRINTER_INIT" envSeparator:";"`
+
+	// Extra printers by name, e.g. featured=/dev/usb/lp1. The DEVICE_PATH
+	// printer is registered as DefaultPrinter. PrintRules route tips to a
+	// named printer; a tip matching no rule prints on the default.
+	DefaultPrinter string            `env:"DEFAULT_PRINTER" envDefault:"default"`
+	Printers       map[string]string `env:"PRINTERS" envKeyValSeparator:"="`
+	PrintRules     []PrintRule       `env:"PRINT_RULES" envSeparator:";"` // e.g. featured:min=50;eu:currency=EUR
+
+	// Receipts beyond PrintRatePerMinute, after an initial burst of
+	// PrintBurst, are queued and printed as the rate allows. 0 disables the
+	// limit.
+	PrintRatePerMinute int `env:"PRINT_RATE_PER_MINUTE" envDefault:"0"`
+	PrintBurst         int `env:"PRINT_BURST" envDefault:"5"`
+
+	// Optional PNG or BMP printed above every receipt, scaled down to
+	// PrinterDotWidth if wider.
+	HeaderImagePath string `env:"HEADER_IMAGE_PATH"`
+	PrinterDotWidth int    `env:"PRINTER_DOT_WIDTH" envDefault:"384"` // 384 for 58mm, 576 for 80mm paper
+
+	// How receipts end: an optional dashed line, FeedLinesAfter blank lines so
+	// the cutter clears the text, then a full or partial cut.
+	PrintSeparator bool   `env:"PRINT_SEPARATOR" envDefault:"false"`
+	FeedLinesAfter int    `env:"FEED_LINES_AFTER" envDefault:"3"`
+	CutMode        string `env:"CUT_MODE" envDefault:"full"` // full or partial
+
+	// CompactReceipt prints each tip as a line or two, with no header image,
+	// footer, QR code or separator, and only CompactFeedLines before the cut,
+	// to save paper. SIGUSR2 toggles it while running.
+	CompactReceipt   bool `env:"COMPACT_RECEIPT" envDefault:"false"`
+	CompactFeedLines int  `env:"COMPACT_FEED_LINES" envDefault:"1"`
+
+	// Lines showing the amount of tips worth more than EmphasizeAmountOver in
+	// BaseCurrency print bold at double size. 0 disables it.
+	EmphasizeAmountOver float64 `env:"EMPHASIZE_AMOUNT_OVER" envDefault:"0"`
+
+	// After a receipt for a tip worth at least TipAlertMinAmount in
+	// BaseCurrency prints, the printer can open the cash drawer on its kick
+	// port and beep. Pulse times are limited to 510ms.
+	KickDrawerOnTip   bool          `env:"KICK_DRAWER_ON_TIP" envDefault:"false"`
+	BeepOnTip         bool          `env:"BEEP_ON_TIP" envDefault:"false"`
+	TipAlertMinAmount float64       `env:"TIP_ALERT_MIN_AMOUNT" envDefault:"0"`
+	DrawerPin         int           `env:"DRAWER_PIN" envDefault:"2"` // kick connector pin, 2 or 5
+	DrawerPulseOn     time.Duration `env:"DRAWER_PULSE_ON" envDefault:"50ms"`
+	DrawerPulseOff    time.Duration `env:"DRAWER_PULSE_OFF" envDefault:"500ms"`
+	BeepTimes         int           `env:"BEEP_TIMES" envDefault:"2"` // 1-9
+	BeepDuration      time.Duration `env:"BEEP_DURATION" envDefault:"100ms"`
+
+	SanitizeMode    string `env:"SANITIZE_MODE" envDefault:"transliterate"` // strip, replace or transliterate non-ASCII text
+	CodePage        string `env:"CODE_PAGE"`                                // cp437, cp850 or katakana; empty prints ASCII only
+	ReceiptLanguage string `env:"RECEIPT_LANGUAGE" envDefault:"en"`         // en, de, fr or es
+
+	// Printed and spoken messages are filtered: words on MessageBlocklist are
+	// redacted or the message is suppressed, per MessageFilterMode, and links
+	// are removed if StripMessageURLs is set. Logged tips keep the original.
+	MessageBlocklist  []string `env:"MESSAGE_BLOCKLIST"`
+	MessageFilterMode string   `env:"MESSAGE_FILTER_MODE" envDefault:"redact"` // redact or suppress
+	StripMessageURLs  bool     `env:"STRIP_MESSAGE_URLS" envDefault:"false"`
+	MaxMessageLength  int      `env:"MAX_MESSAGE_LENGTH" envDefault:"200"` // most printed message runes, 0 for no limit
+	MaxMessageLines   int      `env:"MAX_MESSAGE_LINES" envDefault:"5"`    // most printed message lines before wrapping, 0 for no limit
+
+	// Usernames on receipts are cut to MaxUsernameLength runes, and further
+	// so the built-in "Tip from X: $5.00" line fits on one line.
+	MaxUsernameLength int `env:"MAX_USERNAME_LENGTH" envDefault:"0"` // 0 for no limit
+
+	// ReceiptTemplate is a text/template for the printed receipt. Empty means
+	// the built-in layout.
+	ReceiptTemplate string `env:"RECEIPT_TEMPLATE"`
+
+	// One of FooterMessages, if any, ends each tip receipt, e.g. a thank-you
+	// line. Messages are separated by | so they may contain commas.
+	FooterMessages []string `env:"FOOTER_MESSAGES" envSeparator:"|"`
+	FooterMode     string   `env:"FOOTER_MODE" envDefault:"random"` // random or rotate
+
+	// Optional QR code printed below the receipt text. QRURLTemplate is a
+	// text/template with the same fields as ReceiptTemplate.
+	PrintQRCode   bool   `env:"PRINT_QR_CODE" envDefault:"false"`
+	QRURLTemplate string `env:"QR_URL_TEMPLATE"`
+	QRCodeSize    uint8  `env:"QR_CODE_SIZE" envDefault:"6"` // module size in dots, 1-16
+
+	TipLogPath string `env:"TIP_LOG_PATH"` // append every received tip to this JSONL file
+	TipDBPath  string `env:"TIP_DB_PATH"`  // keep tips in this SQLite database; needs a build with -tags sqlite
+
+	// Tips already handled within DedupWindow are skipped, e.g. when Astro
+	// re-delivers recent tips after a reconnect.
+	DedupWindow   time.Duration `env:"DEDUP_WINDOW" envDefault:"10m"`
+	DedupCapacity int           `env:"DEDUP_CAPACITY" envDefault:"1000"`
+	DedupPath     string        `env:"DEDUP_PATH"` // keep seen tips in this JSON file across restarts
+
+	// Anti-spam: once a donor has sent SpamMaxIdentical tips with the same
+	// message, each less than SpamWindow after the last, further ones are
+	// logged but held, and printed as one receipt with their count when the
+	// flood stops. 0 disables it.
+	SpamWindow       time.Duration `env:"SPAM_WINDOW" envDefault:"0s"`
+	SpamMaxIdentical int           `env:"SPAM_MAX_IDENTICAL" envDefault:"2"`
+	SpamPrintSummary bool          `env:"SPAM_PRINT_SUMMARY" envDefault:"true"` // false drops the held tips from print
+
+	// Optional webhook that receives every tip as JSON, signed with
+	// WebhookSecret in the X-Tipfax-Signature header.
+	WebhookURL     string        `env:"WEBHOOK_URL"`
+	WebhookSecret  string        `env:"WEBHOOK_SECRET"`
+	WebhookTimeout time.Duration `env:"WEBHOOK_TIMEOUT" envDefault:"5s"`
+
+	// WebhookOutboxDir, if set, persists undelivered webhook payloads so they
+	// are retried until delivered, across restarts.
+	WebhookOutboxDir string `env:"WEBHOOK_OUTBOX_DIR"`
+
+	// Optional chat announcements of every tip through incoming webhooks,
+	// sent with WebhookTimeout.
+	DiscordWebhookURL string `env:"DISCORD_WEBHOOK_URL"`
+	SlackWebhookURL   string `env:"SLACK_WEBHOOK_URL"`
+
+	SummaryTime string `env:"SUMMARY_TIME"` // HH:MM, local time, to print a daily tip summary
+
+	// Optional spoken announcement of every tip. TTSCommand is a command line
+	// whose words are text/templates over Username, Amount, Currency, Message
+	// and Text, a ready-made sentence.
+	TTS                 bool   `env:"TTS" envDefault:"false"`
+	TTSCommand          string `env:"TTS_COMMAND" envDefault:"espeak {{.Text}}"`
+	TTSReadMessage      bool   `env:"TTS_READ_MESSAGE" envDefault:"false"`
+	TTSMaxMessageLength int    `env:"TTS_MAX_MESSAGE_LENGTH" envDefault:"100"` // characters of the message read out
+
+	DesktopNotifications bool `env:"DESKTOP_NOTIFICATIONS" envDefault:"false"` // show an OS notification for every tip
+
+	AstroURL string `env:"ASTRO_URL" envDefault:"wss://astro.streamelements.com/"` // Astro WebSocket endpoint, e.g. a staging or mock server
+
+	// Connection settings for proxies and corporate CAs. Without ProxyURL the
+	// HTTPS_PROXY and NO_PROXY environment variables are honored.
+	ProxyURL              string        `env:"PROXY_URL"`
+	HandshakeTimeout      time.Duration `env:"HANDSHAKE_TIMEOUT" envDefault:"10s"`
+	TLSCAFile             string        `env:"TLS_CA_FILE"`                                 // extra PEM CA certificates to trust
+	TLSInsecureSkipVerify bool          `env:"TLS_INSECURE_SKIP_VERIFY" envDefault:"false"` // for testing only
+
+	MaxReconnectAttempts int           `env:"MAX_RECONNECT_ATTEMPTS" envDefault:"0"` // give up after this many failed reconnects in a row, 0 retries forever
+	PingInterval         time.Duration `env:"PING_INTERVAL" envDefault:"20s"`        // WebSocket keepalive ping interval
+
+	// ConnectTimeout bounds the whole dial, including the proxy, TLS and the
+	// WebSocket handshake. SubscribeTimeout bounds the wait for Astro to
+	// answer each subscribe request. The connection is considered dead if
+	// nothing, not even a pong, arrives for ReadTimeout, which must be longer
+	// than PingInterval.
+	ConnectTimeout   time.Duration `env:"CONNECT_TIMEOUT" envDefault:"15s"`
+	SubscribeTimeout time.Duration `env:"SUBSCRIBE_TIMEOUT" envDefault:"10s"`
+	ReadTimeout      time.Duration `env:"READ_TIMEOUT" envDefault:"60s"`
+
+	// Optional listener for /healthz and /readyz. /healthz fails if nothing was
+	// received from Astro (including pongs) for longer than HealthMaxSilence.
+	HealthAddr       string        `env:"HEALTH_ADDR"`
+	HealthMaxSilence time.Duration `env:"HEALTH_MAX_SILENCE" envDefault:"90s"`
+
+	// HeartbeatInterval is how often to log the connection state and the age
+	// of the last frame, at warn level once it exceeds HealthMaxSilence.
+	// 0 disables the heartbeat.
+	HeartbeatInterval time.Duration `env:"HEARTBEAT_INTERVAL" envDefault:"0"`
+
+	// FrameStatsInterval is how often to log the counts of frames read from
+	// Astro by type and topic. 0 turns it off.
+	FrameStatsInterval time.Duration `env:"FRAME_STATS_INTERVAL" envDefault:"0"`
+
+	// Optional Prometheus metrics listener. It shares the health listener when
+	// both use the same address.
+	MetricsAddr string `env:"METRICS_ADDR"`
+	MetricsPath string `env:"METRICS_PATH" envDefault:"/metrics"`
+
+	// Moderated tips are held back until approved, and dropped if denied or
+	// left unresolved for longer than PendingTipTTL.
+	PrintOnlyApproved bool          `env:"PRINT_ONLY_APPROVED" envDefault:"false"`
+	PendingTipTTL     time.Duration `env:"PENDING_TIP_TTL" envDefault:"30m"`
+	PendingTipsPath   string        `env:"PENDING_TIPS_PATH"` // keep held tips in this JSON file across restarts
+
+	// Only tips with one of PrintableStatuses are printed and counted.
+	// Tips without a status, which older integrations send, are "missing".
+	// Refunded and charged back tips never print; if one was printed before,
+	// a warning is logged and, with PrintRefundNotices, a notice printed.
+	PrintableStatuses  []string `env:"PRINTABLE_STATUSES" envDefault:"completed,approved,missing"`
+	PrintRefundNotices bool     `env:"PRINT_REFUND_NOTICES" envDefault:"false"`
+
+	// PrintReadyReceipt prints a short notice once tipfax is first subscribed
+	// to tips, and with PrintOnReconnect after every reconnect too.
+	PrintReadyReceipt bool `env:"PRINT_READY_RECEIPT" envDefault:"false"`
+	PrintOnReconnect  bool `env:"PRINT_ON_RECONNECT" envDefault:"false"`
+
+	// Tips from providers not on ProviderAllowlist, or on ProviderBlocklist,
+	// are logged but not printed. Empty lists allow every provider.
+	ProviderAllowlist []string `env:"PROVIDER_ALLOWLIST"` // e.g. paypal,streamelements
+	ProviderBlocklist []string `env:"PROVIDER_BLOCKLIST"`
+
+	// Tips in currencies not on CurrencyAllowlist are logged but not printed.
+	// An empty list allows every currency. Tips without a currency are taken
+	// to be in DefaultCurrency, or BaseCurrency if that is empty.
+	CurrencyAllowlist []string `env:"CURRENCY_ALLOWLIST"` // e.g. USD,EUR
+	DefaultCurrency   string   `env:"DEFAULT_CURRENCY"`
+
+	// AmountInMinorUnits maps provider names, matched ignoring case, to
+	// whether they send amounts in minor units, e.g. cents, which are
+	// converted to major units per the tip's currency, e.g. kofi:true.
+	AmountInMinorUnits map[string]bool `env:"AMOUNT_IN_MINOR_UNITS"`
+
+	// Tips worth less than MinPrintAmount in BaseCurrency are logged but not
+	// printed. CurrencyRates maps a currency code to its value in BaseCurrency.
+	BaseCurrency   string             `env:"BASE_CURRENCY" envDefault:"USD"`
+	MinPrintAmount float64            `env:"MIN_PRINT_AMOUNT" envDefault:"0"`
+	CurrencyRates  map[string]float64 `env:"CURRENCY_RATES"` // e.g. EUR:1.08,GBP:1.27
+
+	// CurrencyFormats controls how amounts are shown per currency, e.g.
+	// USD=$%s;EUR=%s €|,|.;JPY=¥%s|.|,|0. Other currencies print as "12.34 CODE".
+	CurrencyFormats CurrencyFormats `env:"CURRENCY_FORMATS"`
+
+	// Donation matching for special events, e.g. a "double donations" hour.
+	// A multiplier of 0 or 1 disables matching; a zero start/end leaves that
+	// side of the window open.
+	MatchMultiplier float64   `env:"MATCH_MULTIPLIER" envDefault:"0"`
+	MatchStart      time.Time `env:"MATCH_START"` // RFC3339
+	MatchEnd        time.Time `env:"MATCH_END"`   // RFC3339
+}
+
+// MatchActive reports whether donation matching applies at the given time.
+func (c *Config) MatchActive(now time.Time) bool {
+	if c.MatchMultiplier <= 0 || c.MatchMultiplier == 1 {
+		return false
+	}
+	if !c.MatchStart.IsZero() && now.Before(c.MatchStart) {
+		return false
+	}
+	if !c.MatchEnd.IsZero() && !now.Before(c.MatchEnd) {
+		return false
+	}
+	return true
+}
+
+// PrintRule routes tips to a named printer. A tip matches if it is worth at
+// least MinAmount (in BaseCurrency when a rate is known) and, if Currency is
+// set, was sent in that currency.
+type PrintRule struct {
+	Printer   string
+	MinAmount float64
+	Currency  string
+}
+
+// UnmarshalText parses a rule of the form printer[:min=N][:currency=CODE].
+func (r *PrintRule) UnmarshalText(text []byte) error {
+	parts := strings.Split(string(text), ":")
+	r.Printer = strings.TrimSpace(parts[0])
+	if r.Printer == "" {
+		return fmt.Errorf("print rule %q: missing printer name", text)
+	}
+
+	for _, part := range parts[1:] {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return fmt.Errorf("print rule %q: expected key=value, got %q", text, part)
+		}
+		switch strings.TrimSpace(key) {
+		case "min":
+			min, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				return fmt.Errorf("print rule %q: invalid min: %w", text, err)
+			}
+			r.MinAmount = min
+		case "currency":
+			r.Currency = strings.ToUpper(strings.TrimSpace(value))
+		default:
+			return fmt.Errorf("print rule %q: unknown key %q", text, key)
+		}
+	}
+	return nil
+}
+
+// CurrencyFormat describes how to show amounts in one currency.
+type CurrencyFormat struct {
+	Pattern      string // "%s" is replaced by the number, e.g. "$%s" or "%s €"
+	DecimalSep   string
+	ThousandsSep string
+	Decimals     int
 }
 
+// CurrencyFormats maps a currency code to its format.
+type CurrencyFormats map[string]CurrencyFormat
+
+// UnmarshalText parses ";"-separated CODE=pattern[|decimal[|thousands[|decimals]]]
+// entries. The decimal separator defaults to ".", thousands grouping to none
+// and decimals to 2.
+func (f *CurrencyFormats) UnmarshalText(text []byte) error {
+	formats := make(CurrencyFormats)
+	for _, entry := range strings.Split(string(text), ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		code, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fmt.Errorf("currency format %q: expected CODE=pattern", entry)
+		}
+
+		parts := strings.Split(spec, "|")
+		if len(parts) > 4 {
+			return fmt.Errorf("currency format %q: too many fields", entry)
+		}
+		cf := CurrencyFormat{Pattern: parts[0], DecimalSep: ".", Decimals: 2}
+		if !strings.Contains(cf.Pattern, "%s") {
+			return fmt.Errorf("currency format %q: pattern must contain %%s", entry)
+		}
+		if len(parts) > 1 && parts[1] != "" {
+			cf.DecimalSep = parts[1]
+		}
+		if len(parts) > 2 {
+			cf.ThousandsSep = parts[2]
+		}
+		if len(parts) > 3 {
+			n, err := strconv.Atoi(strings.TrimSpace(parts[3]))
+			if err != nil || n < 0 || n > 8 {
+				return fmt.Errorf("currency format %q: decimals must be between 0 and 8", entry)
+			}
+			cf.Decimals = n
+		}
+		formats[strings.ToUpper(strings.TrimSpace(code))] = cf
+	}
+	*f = formats
+	return nil
+}
+
+// Validate checks the whole configuration and reports every problem found,
+// joined into a single error.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	if c.SeJWTToken == "" {
+		errs = append(errs, errors.New("SE_JWT_TOKEN is empty and SE_JWT_TOKEN_FILE is not set"))
+	} else if err := ValidateJWT(c.SeJWTToken); err != nil {
+		errs = append(errs, fmt.Errorf("SE_JWT_TOKEN: %w", err))
+	}
+	if c.SeModerationJWTToken != "" {
+		if err := ValidateJWT(c.SeModerationJWTToken); err != nil {
+			errs = append(errs, fmt.Errorf("SE_MODERATION_JWT_TOKEN: %w", err))
+		}
+	}
+
+	check(c.ChannelName != "", "CHANNEL_NAME is empty")
+	for name, token := range c.ChannelTokens {
+		check(name != c.ChannelName, "CHANNEL_TOKENS: %q is already the name of the SE_JWT_TOKEN channel", name)
+		if err := ValidateJWT(token); err != nil {
+			errs = append(errs, fmt.Errorf("CHANNEL_TOKENS: channel %q: %w", name, err))
+		}
+	}
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.LogLevel)) == nil, "LOG_LEVEL must be debug, info, warn or error, got %q", c.LogLevel)
+	check(c.LogFormat == "text" || c.LogFormat == "json", "LOG_FORMAT must be text or json, got %q", c.LogFormat)
+
+	// Printers
+	check(c.DryRun || c.DevicePath != "", "DEVICE_PATH is empty")
+	check(c.DefaultPrinter != "", "DEFAULT_PRINTER is empty")
+	for name, path := range c.Printers {
+		check(name != c.DefaultPrinter, "PRINTERS: %q is already the name of the default printer", name)
+		check(path != "", "PRINTERS: printer %q has no device path", name)
+	}
+	switch c.PrinterTransport {
+	case "usb", "serial":
+	case "tcp":
+		check(c.PrinterConnectTimeout > 0, "PRINTER_CONNECT_TIMEOUT must be positive, got %s", c.PrinterConnectTimeout)
+		check(c.PrinterKeepAlive >= 0, "PRINTER_KEEPALIVE must not be negative, got %s", c.PrinterKeepAlive)
+		if !c.DryRun {
+			_, _, err := net.SplitHostPort(c.DevicePath)
+			check(c.DevicePath == "" || err == nil, "DEVICE_PATH must be host:port with PRINTER_TRANSPORT=tcp, got %q", c.DevicePath)
+			for name, path := range c.Printers {
+				_, _, err := net.SplitHostPort(path)
+				check(path == "" || err == nil, "PRINTERS: printer %q must be host:port with PRINTER_TRANSPORT=tcp, got %q", name, path)
+			}
+		}
+	default:
+		check(false, "PRINTER_TRANSPORT must be usb, serial or tcp, got %q", c.PrinterTransport)
+	}
+	for _, rule := range c.PrintRules {
+		_, known := c.Printers[rule.Printer]
+		check(known || rule.Printer == c.DefaultPrinter, "PRINT_RULES: unknown printer %q", rule.Printer)
+		check(rule.MinAmount >= 0, "PRINT_RULES: printer %q has a negative min", rule.Printer)
+	}
+	check(c.PrinterColumns > 0, "PRINTER_COLUMNS must be positive, got %d", c.PrinterColumns)
+	check(c.PrintRetries >= 0, "PRINT_RETRIES must not be negative, got %d", c.PrintRetries)
+	check(c.PrintRetryDelay >= 0, "PRINT_RETRY_DELAY must not be negative, got %s", c.PrintRetryDelay)
+	check(c.PrintQueueSize > 0, "PRINT_QUEUE_SIZE must be positive, got %d", c.PrintQueueSize)
+	check(c.PrinterStatusInterval >= 0, "PRINTER_STATUS_INTERVAL must not be negative, got %s", c.PrinterStatusInterval)
+	check(c.PrintQueueRetryInterval > 0, "PRINT_QUEUE_RETRY_INTERVAL must be positive, got %s", c.PrintQueueRetryInterval)
+	check(c.PrinterDotWidth > 0, "PRINTER_DOT_WIDTH must be positive, got %d", c.PrinterDotWidth)
+	check(c.FeedLinesAfter >= 0, "FEED_LINES_AFTER must not be negative, got %d", c.FeedLinesAfter)
+	check(c.CompactFeedLines >= 0, "COMPACT_FEED_LINES must not be negative, got %d", c.CompactFeedLines)
+	check(c.CutMode == "full" || c.CutMode == "partial", "CUT_MODE must be full or partial, got %q", c.CutMode)
+	check(c.EmphasizeAmountOver >= 0, "EMPHASIZE_AMOUNT_OVER must not be negative, got %g", c.EmphasizeAmountOver)
+	check(c.TipAlertMinAmount >= 0, "TIP_ALERT_MIN_AMOUNT must not be negative, got %g", c.TipAlertMinAmount)
+	check(c.DrawerPin == 2 || c.DrawerPin == 5, "DRAWER_PIN must be 2 or 5, got %d", c.DrawerPin)
+	check(c.DrawerPulseOn >= 2*time.Millisecond && c.DrawerPulseOn <= 510*time.Millisecond, "DRAWER_PULSE_ON must be between 2ms and 510ms, got %s", c.DrawerPulseOn)
+	check(c.DrawerPulseOff >= 2*time.Millisecond && c.DrawerPulseOff <= 510*time.Millisecond, "DRAWER_PULSE_OFF must be between 2ms and 510ms, got %s", c.DrawerPulseOff)
+	check(c.BeepTimes >= 1 && c.BeepTimes <= 9, "BEEP_TIMES must be between 1 and 9, got %d", c.BeepTimes)
+	check(c.BeepDuration > 0, "BEEP_DURATION must be positive, got %s", c.BeepDuration)
+	check(c.PrintRatePerMinute >= 0, "PRINT_RATE_PER_MINUTE must not be negative, got %d", c.PrintRatePerMinute)
+	check(c.PrintBurst > 0, "PRINT_BURST must be positive, got %d", c.PrintBurst)
+	switch c.SanitizeMode {
+	case "strip", "replace", "transliterate":
+	default:
+		errs = append(errs, fmt.Errorf("SANITIZE_MODE must be strip, replace or transliterate, got %q", c.SanitizeMode))
+	}
+	switch strings.ToLower(c.CodePage) {
+	case "", "cp437", "cp850", "katakana":
+	default:
+		errs = append(errs, fmt.Errorf("CODE_PAGE must be cp437, cp850 or katakana, got %q", c.CodePage))
+	}
+	check(c.FooterMode == "random" || c.FooterMode == "rotate", "FOOTER_MODE must be random or rotate, got %q", c.FooterMode)
+	check(c.MessageFilterMode == "redact" || c.MessageFilterMode == "suppress", "MESSAGE_FILTER_MODE must be redact or suppress, got %q", c.MessageFilterMode)
+	check(c.MaxMessageLength >= 0, "MAX_MESSAGE_LENGTH must not be negative, got %d", c.MaxMessageLength)
+	check(c.MaxMessageLines >= 0, "MAX_MESSAGE_LINES must not be negative, got %d", c.MaxMessageLines)
+	check(c.MaxUsernameLength >= 0, "MAX_USERNAME_LENGTH must not be negative, got %d", c.MaxUsernameLength)
+	for _, st := range c.PrintableStatuses {
+		switch strings.ToLower(strings.TrimSpace(st)) {
+		case "completed", "success", "approved", "pending", "missing", "unknown":
+		default:
+			errs = append(errs, fmt.Errorf("PRINTABLE_STATUSES: %q is not a printable status", st))
+		}
+	}
+	check(c.QRCodeSize >= 1 && c.QRCodeSize <= 16, "QR_CODE_SIZE must be between 1 and 16, got %d", c.QRCodeSize)
+
+	// Tips
+	check(c.DedupWindow >= 0, "DEDUP_WINDOW must not be negative, got %s", c.DedupWindow)
+	check(c.DedupCapacity > 0, "DEDUP_CAPACITY must be positive, got %d", c.DedupCapacity)
+	check(c.SpamWindow >= 0, "SPAM_WINDOW must not be negative, got %s", c.SpamWindow)
+	check(c.SpamMaxIdentical > 0, "SPAM_MAX_IDENTICAL must be positive, got %d", c.SpamMaxIdentical)
+	check(c.PendingTipTTL > 0, "PENDING_TIP_TTL must be positive, got %s", c.PendingTipTTL)
+	check(c.MinPrintAmount >= 0, "MIN_PRINT_AMOUNT must not be negative, got %g", c.MinPrintAmount)
+	for currency, rate := range c.CurrencyRates {
+		check(rate > 0, "CURRENCY_RATES: rate for %s must be positive, got %g", currency, rate)
+	}
+	check(c.MatchMultiplier >= 0, "MATCH_MULTIPLIER must not be negative, got %g", c.MatchMultiplier)
+	check(c.MatchStart.IsZero() || c.MatchEnd.IsZero() || c.MatchEnd.After(c.MatchStart),
+		"MATCH_END must be after MATCH_START")
+
+	if c.SummaryTime != "" {
+		_, err := time.Parse("15:04", c.SummaryTime)
+		check(err == nil, "SUMMARY_TIME must be HH:MM, got %q", c.SummaryTime)
+	}
+
+	// Integrations
+	check(!c.TTS || strings.TrimSpace(c.TTSCommand) != "", "TTS_COMMAND is empty")
+	check(c.TTSMaxMessageLength >= 0, "TTS_MAX_MESSAGE_LENGTH must not be negative, got %d", c.TTSMaxMessageLength)
+	if c.WebhookURL != "" {
+		u, err := url.Parse(c.WebhookURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"WEBHOOK_URL must be an absolute http(s) URL, got %q", c.WebhookURL)
+	}
+	check(c.WebhookTimeout > 0, "WEBHOOK_TIMEOUT must be positive, got %s", c.WebhookTimeout)
+	for name, chatURL := range map[string]string{"DISCORD_WEBHOOK_URL": c.DiscordWebhookURL, "SLACK_WEBHOOK_URL": c.SlackWebhookURL} {
+		if chatURL != "" {
+			u, err := url.Parse(chatURL)
+			check(err == nil && u.Scheme == "https" && u.Host != "", "%s must be an absolute https URL, got %q", name, chatURL)
+		}
+	}
+	if u, err := url.Parse(c.AstroURL); err != nil || (u.Scheme != "ws" && u.Scheme != "wss") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("ASTRO_URL must be an absolute ws(s) URL, got %q", c.AstroURL))
+	}
+	if c.ProxyURL != "" {
+		u, err := url.Parse(c.ProxyURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https" || u.Scheme == "socks5") && u.Host != "",
+			"PROXY_URL must be an http(s) or socks5 URL, got %q", c.ProxyURL)
+	}
+	check(c.HandshakeTimeout > 0, "HANDSHAKE_TIMEOUT must be positive, got %s", c.HandshakeTimeout)
+	if c.TLSCAFile != "" {
+		_, err := os.Stat(c.TLSCAFile)
+		check(err == nil, "TLS_CA_FILE: %v", err)
+	}
+	check(c.MaxReconnectAttempts >= 0, "MAX_RECONNECT_ATTEMPTS must not be negative, got %d", c.MaxReconnectAttempts)
+	check(c.PingInterval > 0, "PING_INTERVAL must be positive, got %s", c.PingInterval)
+	check(c.ConnectTimeout > 0, "CONNECT_TIMEOUT must be positive, got %s", c.ConnectTimeout)
+	check(c.SubscribeTimeout > 0, "SUBSCRIBE_TIMEOUT must be positive, got %s", c.SubscribeTimeout)
+	check(c.ReadTimeout > c.PingInterval, "READ_TIMEOUT must be longer than PING_INTERVAL (%s), got %s", c.PingInterval, c.ReadTimeout)
+	check(c.HealthMaxSilence > 0, "HEALTH_MAX_SILENCE must be positive, got %s", c.HealthMaxSilence)
+	check(c.HeartbeatInterval >= 0, "HEARTBEAT_INTERVAL must not be negative, got %s", c.HeartbeatInterval)
+	check(c.FrameStatsInterval >= 0, "FRAME_STATS_INTERVAL must not be negative, got %s", c.FrameStatsInterval)
+	check(strings.HasPrefix(c.MetricsPath, "/"), "METRICS_PATH must start with /, got %q", c.MetricsPath)
+
+	return errors.Join(errs...)
+}
+
+// LoadToken returns the current JWT: the contents of SeJWTTokenFile if set,
+// and otherwise SE_JWT_TOKEN.
+func (c *Config) LoadToken() (string, error) {
+	if c.SeJWTTokenFile != "" {
+		b, err := os.ReadFile(c.SeJWTTokenFile)
+		if err != nil {
+			return "", fmt.Errorf("read SE_JWT_TOKEN_FILE: %w", err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	return c.SeJWTToken, nil
+}
+
+// New reads the configuration from the environment and exits on errors. Use
+// LoadConfig to also read a config file.
 func New() *Config {
-	cfg := &Config{}
-	err := env.Parse(cfg)
+	cfg, err := LoadConfig("")
 	if err != nil {
 		log.Fatalf("Failed to parse config: %v", err)
 	}
-
 	return cfg
 }
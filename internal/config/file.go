@@ -0,0 +1,191 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+
+	env "github.com/caarlos0/env/v11"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadConfig builds the configuration from, in increasing precedence, the
+// defaults, the YAML file at path and the environment. File keys are the
+// environment variable names, in any case, e.g. device_path: /dev/usb/lp1.
+// Lists may be written as YAML sequences and maps as YAML mappings. Unknown
+// keys are logged as warnings. If path is "", only the environment is read.
+func LoadConfig(path string) (*Config, error) {
+	environ := make(map[string]string)
+	fileLines := make(map[string]int) // line of each key taken from the file
+	if path != "" {
+		values, lines, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range values {
+			environ[key] = value
+			fileLines[key] = lines[key]
+		}
+	}
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		environ[key] = value
+		delete(fileLines, key)
+	}
+
+	cfg := &Config{}
+	if err := env.ParseWithOptions(cfg, env.Options{Environment: environ}); err != nil {
+		return nil, fileErrors(err, path, fileLines)
+	}
+
+	if cfg.SeJWTTokenFile != "" {
+		token, err := cfg.LoadToken()
+		if err != nil {
+			return nil, err
+		}
+		cfg.SeJWTToken = token
+	}
+
+	return cfg, nil
+}
+
+// Defaults returns the configuration with every setting at its default,
+// ignoring the environment, so tests don't depend on the shell they run in.
+func Defaults() (*Config, error) {
+	cfg := &Config{}
+	if err := env.ParseWithOptions(cfg, env.Options{Environment: map[string]string{}}); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// readConfigFile reads the YAML mapping at path into environment variable
+// values, and returns the line each key is on.
+func readConfigFile(path string) (map[string]string, map[string]int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	values := make(map[string]string)
+	lines := make(map[string]int)
+	if len(doc.Content) == 0 {
+		return values, lines, nil // empty file
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("%s:%d: expected a mapping of settings", path, root.Line)
+	}
+
+	fields := configFields()
+	var errs []error
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		keyNode, valueNode := root.Content[i], root.Content[i+1]
+		key := strings.ToUpper(keyNode.Value)
+		field, ok := fields[key]
+		if !ok {
+			log.Printf("Warning: %s:%d: unknown config key %q", path, keyNode.Line, keyNode.Value)
+			continue
+		}
+		if valueNode.Tag == "!!null" {
+			continue
+		}
+
+		value, err := nodeValue(valueNode, field)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s:%d: %s: %w", path, valueNode.Line, keyNode.Value, err))
+			continue
+		}
+		values[key] = value
+		lines[key] = keyNode.Line
+	}
+	return values, lines, errors.Join(errs...)
+}
+
+// nodeValue renders a YAML value the way field expects it in the
+// environment: sequences joined with its envSeparator and mappings as
+// key-value pairs joined with its envKeyValSeparator.
+func nodeValue(node *yaml.Node, field reflect.StructField) (string, error) {
+	sep := field.Tag.Get("envSeparator")
+	if sep == "" {
+		sep = ","
+	}
+	kvSep := field.Tag.Get("envKeyValSeparator")
+	if kvSep == "" {
+		kvSep = ":"
+	}
+	textual := reflect.PointerTo(field.Type).Implements(reflect.TypeFor[encoding.TextUnmarshaler]())
+
+	switch {
+	case node.Kind == yaml.ScalarNode:
+		return node.Value, nil
+	case node.Kind == yaml.SequenceNode && field.Type.Kind() == reflect.Slice:
+		items := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return "", fmt.Errorf("line %d: list items must be plain values", item.Line)
+			}
+			items = append(items, item.Value)
+		}
+		return strings.Join(items, sep), nil
+	case node.Kind == yaml.MappingNode && field.Type.Kind() == reflect.Map && !textual:
+		pairs := make([]string, 0, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+			if k.Kind != yaml.ScalarNode || v.Kind != yaml.ScalarNode {
+				return "", fmt.Errorf("line %d: map entries must be plain values", k.Line)
+			}
+			pairs = append(pairs, k.Value+kvSep+v.Value)
+		}
+		return strings.Join(pairs, sep), nil
+	}
+	return "", errors.New("expected a plain value, as in the environment variable")
+}
+
+// configFields returns the Config fields by environment variable name.
+func configFields() map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	t := reflect.TypeFor[Config]()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("env"), ",")
+		if name != "" {
+			fields[name] = f
+		}
+	}
+	return fields
+}
+
+// fileErrors points parse errors for values taken from the config file at
+// the line they came from.
+func fileErrors(err error, path string, fileLines map[string]int) error {
+	var agg env.AggregateError
+	if path == "" || !errors.As(err, &agg) {
+		return err
+	}
+
+	keys := make(map[string]string) // field name -> env name
+	for key, f := range configFields() {
+		keys[f.Name] = key
+	}
+
+	errs := make([]error, 0, len(agg.Errors))
+	for _, e := range agg.Errors {
+		var pe env.ParseError
+		if errors.As(e, &pe) {
+			if line, ok := fileLines[keys[pe.Name]]; ok {
+				e = fmt.Errorf("%s:%d: %s: %w", path, line, strings.ToLower(keys[pe.Name]), pe.Err)
+			}
+		}
+		errs = append(errs, e)
+	}
+	return errors.Join(errs...)
+}
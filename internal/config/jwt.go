@@ -0,0 +1,70 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// ValidateJWT checks that token is structurally a JWT: three dot-separated,
+// base64url-encoded parts whose header and payload are JSON objects. The
+// signature is not verified. An expired token only produces a warning, since
+// the server is the authority on whether it is still accepted.
+func ValidateJWT(token string) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid JWT: expected 3 dot-separated parts, got %d", len(parts))
+	}
+
+	var header map[string]any
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return fmt.Errorf("invalid JWT header: %w", err)
+	}
+
+	var claims struct {
+		Exp *float64 `json:"exp"`
+	}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return fmt.Errorf("invalid JWT payload: %w", err)
+	}
+
+	if claims.Exp != nil {
+		exp := time.Unix(int64(*claims.Exp), 0)
+		if time.Now().After(exp) {
+			slog.Warn("JWT token has expired", "expired_at", exp)
+		}
+	}
+
+	return nil
+}
+
+// JWTExpiry returns the expiry time in token's exp claim. ok is false if the
+// token has no exp claim or can't be decoded.
+func JWTExpiry(token string) (exp time.Time, ok bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+
+	var claims struct {
+		Exp *float64 `json:"exp"`
+	}
+	if err := decodeJWTPart(parts[1], &claims); err != nil || claims.Exp == nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(*claims.Exp), 0), true
+}
+
+func decodeJWTPart(part string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(part, "="))
+	if err != nil {
+		return fmt.Errorf("not base64url: %w", err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("not JSON: %w", err)
+	}
+	return nil
+}
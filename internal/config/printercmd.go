@@ -0,0 +1,85 @@
+package config
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PrinterCommand is one ESC/POS command sent to printers after opening them,
+// see PrinterInit. It parses from name=value:
+//
+//	codepage=16     ESC t, select the character code table
+//	charset=2       ESC R, select an international character set
+//	density=3       GS ( K, print density from -6 to 6
+//	spacing=1       ESC SP, extra dots right of each character
+//	linespacing=30  ESC 3, line spacing in dots
+//	hex=1b7410      raw bytes, for anything else
+type PrinterCommand struct {
+	Text  string // as configured
+	Bytes []byte // what is sent
+}
+
+func (c *PrinterCommand) UnmarshalText(text []byte) error {
+	c.Text = strings.TrimSpace(string(text))
+	name, value, ok := strings.Cut(c.Text, "=")
+	if !ok {
+		return fmt.Errorf("printer command %q: expected name=value", text)
+	}
+	name, value = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(value)
+
+	if name == "hex" {
+		b, err := hex.DecodeString(strings.ReplaceAll(value, " ", ""))
+		if err != nil || len(b) == 0 {
+			return fmt.Errorf("printer command %q: expected hex bytes, e.g. 1b7410", text)
+		}
+		c.Bytes = b
+		return nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("printer command %q: expected a number", text)
+	}
+	byteArg := func(lo, hi int) (byte, error) {
+		if n < lo || n > hi {
+			return 0, fmt.Errorf("printer command %q: %s must be between %d and %d", text, name, lo, hi)
+		}
+		return byte(n), nil
+	}
+
+	switch name {
+	case "codepage":
+		m, err := byteArg(0, 255)
+		c.Bytes = []byte{0x1b, 't', m}
+		return err
+	case "charset":
+		m, err := byteArg(0, 255)
+		c.Bytes = []byte{0x1b, 'R', m}
+		return err
+	case "density":
+		// Negative densities are sent as 250-255.
+		m, err := byteArg(-6, 6)
+		c.Bytes = []byte{0x1d, '(', 'K', 2, 0, '1', m}
+		return err
+	case "spacing":
+		m, err := byteArg(0, 255)
+		c.Bytes = []byte{0x1b, ' ', m}
+		return err
+	case "linespacing":
+		m, err := byteArg(0, 255)
+		c.Bytes = []byte{0x1b, '3', m}
+		return err
+	}
+	return fmt.Errorf("printer command %q: unknown command %q", text, name)
+}
+
+// PrinterInitBytes returns the PrinterInit commands as one byte sequence.
+func (c *Config) PrinterInitBytes() []byte {
+	var b []byte
+	for _, cmd := range c.PrinterInit {
+		b = append(b, cmd.Bytes...)
+	}
+	return b
+}
@@ -0,0 +1,100 @@
+package fax
+
+import "strings"
+
+// CodePage is a printer character code table: ASCII plus up to 128 more
+// characters in bytes 0x80-0xFF. Text written to a Device with a code page
+// set is encoded to it.
+type CodePage struct {
+	Name   string
+	Number byte // n of ESC t n, per the Epson numbering
+
+	encode map[rune]byte
+}
+
+func newCodePage(name string, number byte, upper [128]rune) *CodePage {
+	cp := &CodePage{Name: name, Number: number, encode: make(map[rune]byte)}
+	for i, r := range upper {
+		if r != 0 {
+			cp.encode[r] = byte(0x80 + i)
+		}
+	}
+	return cp
+}
+
+// codePages are the supported code pages by name.
+var codePages = map[string]*CodePage{
+	"cp437":    newCodePage("cp437", 0, cp437),
+	"katakana": newCodePage("katakana", 1, katakana()),
+	"cp850":    newCodePage("cp850", 2, cp850),
+}
+
+// LookupCodePage returns the code page called name, e.g. "cp850", ignoring
+// case.
+func LookupCodePage(name string) (*CodePage, bool) {
+	cp, ok := codePages[strings.ToLower(strings.TrimSpace(name))]
+	return cp, ok
+}
+
+// Select returns the ESC t command that switches the printer to cp.
+func (cp *CodePage) Select() []byte {
+	return []byte{0x1b, 't', cp.Number}
+}
+
+// Has reports whether r can be printed in cp.
+func (cp *CodePage) Has(r rune) bool {
+	if r < 0x80 {
+		return true
+	}
+	_, ok := cp.encode[r]
+	return ok
+}
+
+// Encode converts s from UTF-8 to cp's bytes. Runes cp doesn't have become
+// '?'.
+func (cp *CodePage) Encode(s string) string {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch c, ok := cp.encode[r]; {
+		case r < 0x80:
+			b = append(b, byte(r))
+		case ok:
+			b = append(b, c)
+		default:
+			b = append(b, '?')
+		}
+	}
+	return string(b)
+}
+
+var cp437 = [128]rune{
+	'Ç', 'ü', 'é', 'â', 'ä', 'à', 'å', 'ç', 'ê', 'ë', 'è', 'ï', 'î', 'ì', 'Ä', 'Å',
+	'É', 'æ', 'Æ', 'ô', 'ö', 'ò', 'û', 'ù', 'ÿ', 'Ö', 'Ü', '¢', '£', '¥', '₧', 'ƒ',
+	'á', 'í', 'ó', 'ú', 'ñ', 'Ñ', 'ª', 'º', '¿', '⌐', '¬', '½', '¼', '¡', '«', '»',
+	'░', '▒', '▓', '│', '┤', '╡', '╢', '╖', '╕', '╣', '║', '╗', '╝', '╜', '╛', '┐',
+	'└', '┴', '┬', '├', '─', '┼', '╞', '╟', '╚', '╔', '╩', '╦', '╠', '═', '╬', '╧',
+	'╨', '╤', '╥', '╙', '╘', '╒', '╓', '╫', '╪', '┘', '┌', '█', '▄', '▌', '▐', '▀',
+	'α', 'ß', 'Γ', 'π', 'Σ', 'σ', 'µ', 'τ', 'Φ', 'Θ', 'Ω', 'δ', '∞', 'φ', 'ε', '∩',
+	'≡', '±', '≥', '≤', '⌠', '⌡', '÷', '≈', '°', '∙', '·', '√', 'ⁿ', '²', '■', '\u00a0',
+}
+
+var cp850 = [128]rune{
+	'Ç', 'ü', 'é', 'â', 'ä', 'à', 'å', 'ç', 'ê', 'ë', 'è', 'ï', 'î', 'ì', 'Ä', 'Å',
+	'É', 'æ', 'Æ', 'ô', 'ö', 'ò', 'û', 'ù', 'ÿ', 'Ö', 'Ü', 'ø', '£', 'Ø', '×', 'ƒ',
+	'á', 'í', 'ó', 'ú', 'ñ', 'Ñ', 'ª', 'º', '¿', '®', '¬', '½', '¼', '¡', '«', '»',
+	'░', '▒', '▓', '│', '┤', 'Á', 'Â', 'À', '©', '╣', '║', '╗', '╝', '¢', '¥', '┐',
+	'└', '┴', '┬', '├', '─', '┼', 'ã', 'Ã', '╚', '╔', '╩', '╦', '╠', '═', '╬', '¤',
+	'ð', 'Ð', 'Ê', 'Ë', 'È', 'ı', 'Í', 'Î', 'Ï', '┘', '┌', '█', '▄', '¦', 'Ì', '▀',
+	'Ó', 'ß', 'Ô', 'Ò', 'õ', 'Õ', 'µ', 'þ', 'Þ', 'Ú', 'Û', 'Ù', 'ý', 'Ý', '¯', '´',
+	'\u00ad', '±', '‗', '¾', '¶', '§', '÷', '¸', '°', '¨', '·', '¹', '³', '²', '■', '\u00a0',
+}
+
+// katakana has the half-width katakana of JIS X 0201 at 0xA1-0xDF. The
+// graphics characters the printer has at the other positions are left out.
+func katakana() [128]rune {
+	var upper [128]rune
+	for b := 0xA1; b <= 0xDF; b++ {
+		upper[b-0x80] = rune(0xFF61 + b - 0xA1)
+	}
+	return upper
+}
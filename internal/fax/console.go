@@ -0,0 +1,64 @@
+package fax
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// ConsolePrinter is a Printer that echoes each receipt to an io.Writer
+// instead of a device. It is used for dry runs without printer hardware.
+type ConsolePrinter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	buf strings.Builder
+}
+
+func NewConsolePrinter(w io.Writer) *ConsolePrinter {
+	return &ConsolePrinter{w: w}
+}
+
+func (p *ConsolePrinter) Write(data string) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.buf.WriteString(data)
+}
+
+func (p *ConsolePrinter) LineFeed() (int, error) {
+	return p.Write("\n")
+}
+
+// WriteRaw stands in for raw printer commands, such as images, with a
+// placeholder line.
+func (p *ConsolePrinter) WriteRaw(data []byte) (int, error) {
+	if _, err := p.Write(fmt.Sprintf("[%d bytes of printer commands]\n", len(data))); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// PrintAndCut writes the buffered receipt between cut markers and resets the
+// buffer.
+func (p *ConsolePrinter) PrintAndCut() error {
+	return p.flush("------- cut -------")
+}
+
+// PrintAndPartialCut is like PrintAndCut with a partial cut marker.
+func (p *ConsolePrinter) PrintAndPartialCut() error {
+	return p.flush("--- partial cut ---")
+}
+
+func (p *ConsolePrinter) flush(cut string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	receipt := p.buf.String()
+	p.buf.Reset()
+	if receipt != "" && !strings.HasSuffix(receipt, "\n") {
+		receipt += "\n"
+	}
+
+	_, err := fmt.Fprintf(p.w, "----- receipt -----\n%s%s\n", receipt, cut)
+	return err
+}
@@ -0,0 +1,131 @@
+package fax
+
+import (
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/securityguy/escpos"
+)
+
+// Reopener is implemented by printers that can re-establish their connection
+// after an I/O error, e.g. once a USB cable is plugged back in.
+type Reopener interface {
+	Reopen() error
+}
+
+// Device is an ESC/POS printer on a local device file, USB or serial, or on
+// the network. Unlike a bare *escpos.Escpos it can be reopened, which also
+// clears the sticky write error of escpos' internal buffer.
+type Device struct {
+	*escpos.Escpos
+
+	mu     sync.Mutex
+	open   func() (io.ReadWriteCloser, error)
+	config escpos.PrinterConfig
+	init   []byte // sent after every open
+	file   io.ReadWriteCloser
+
+	noStatus bool      // the printer didn't answer a status query
+	codePage *CodePage // text is encoded to it if set, see SetCodePage
+}
+
+// OpenDevice opens the printer at path and applies config to it. init, if
+// any, is sent right away and again on every Reopen, e.g. to select a code
+// page.
+func OpenDevice(path string, config escpos.PrinterConfig, init []byte) (*Device, error) {
+	return newDevice(func() (io.ReadWriteCloser, error) {
+		return os.OpenFile(path, os.O_RDWR, 0)
+	}, config, init)
+}
+
+// OpenNetworkDevice connects to a network printer at addr, host:port, usually
+// port 9100, waiting up to timeout. TCP keepalives are sent every keepAlive
+// so a printer that dropped off the network is noticed; 0 uses the system
+// default. Reopen reconnects. Otherwise it is like OpenDevice.
+func OpenNetworkDevice(addr string, timeout, keepAlive time.Duration, config escpos.PrinterConfig, init []byte) (*Device, error) {
+	dialer := net.Dialer{Timeout: timeout, KeepAlive: keepAlive}
+	return newDevice(func() (io.ReadWriteCloser, error) {
+		return dialer.Dial("tcp", addr)
+	}, config, init)
+}
+
+func newDevice(open func() (io.ReadWriteCloser, error), config escpos.PrinterConfig, init []byte) (*Device, error) {
+	d := &Device{open: open, config: config, init: init}
+	if err := d.Reopen(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Reopen closes the device, if open, and opens it again. The old connection
+// is closed first, as network printers often take only one at a time.
+func (d *Device) Reopen() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.file != nil {
+		d.file.Close()
+	}
+	file, err := d.open()
+	if err != nil {
+		return err
+	}
+
+	p := escpos.New(file)
+	p.SetConfig(d.config)
+	p.Size(1, 1)
+	if len(d.init) > 0 {
+		p.WriteRaw(d.init)
+		if err := p.Print(); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	d.file = file
+	d.Escpos = p
+	d.noStatus = false
+	return nil
+}
+
+// SetCodePage makes Write encode text to cp, or pass it through unchanged if
+// cp is nil. The printer must be switched to cp as well, e.g. by putting
+// cp.Select() in the init bytes.
+func (d *Device) SetCodePage(cp *CodePage) {
+	d.codePage = cp
+}
+
+// Write prints data, encoded to the code page if one is set.
+func (d *Device) Write(data string) (int, error) {
+	if d.codePage != nil {
+		data = d.codePage.Encode(data)
+	}
+	return d.Escpos.Write(data)
+}
+
+// Emphasize sets the style escpos sends with every Write: bold at double
+// width and height, or back to normal.
+func (d *Device) Emphasize(on bool) {
+	if on {
+		d.Bold(true).Size(2, 2)
+	} else {
+		d.Bold(false).Size(1, 1)
+	}
+}
+
+// PrintAndPartialCut sends the buffered data to the printer and performs a
+// partial cut.
+func (d *Device) PrintAndPartialCut() error {
+	if _, err := d.WriteRaw([]byte{0x1d, 'V', 'B', 0}); err != nil {
+		return err
+	}
+	return d.Print()
+}
+
+func (d *Device) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.file.Close()
+}
@@ -0,0 +1,42 @@
+package fax
+
+import "time"
+
+// DrawerKicker is implemented by printers that can open a cash drawer wired
+// to their kick port.
+type DrawerKicker interface {
+	// KickDrawer pulses connector pin 2 or 5 on for on, then off for off.
+	KickDrawer(pin int, on, off time.Duration) error
+}
+
+// Beeper is implemented by printers with a buzzer.
+type Beeper interface {
+	// Beep sounds the buzzer times times, each for about d.
+	Beep(times int, d time.Duration) error
+}
+
+// KickDrawer sends ESC p. Pulse times are sent in 2ms units, up to 510ms.
+func (d *Device) KickDrawer(pin int, on, off time.Duration) error {
+	m := byte(0)
+	if pin == 5 {
+		m = 1
+	}
+	if _, err := d.WriteRaw([]byte{0x1b, 'p', m, units(on, 2*time.Millisecond), units(off, 2*time.Millisecond)}); err != nil {
+		return err
+	}
+	return d.Print()
+}
+
+// Beep sends ESC B, supported by Epson TM printers with a buzzer. The
+// duration is sent in 50ms units, and times is limited to 9.
+func (d *Device) Beep(times int, dur time.Duration) error {
+	if _, err := d.WriteRaw([]byte{0x1b, 'B', byte(min(max(times, 1), 9)), units(dur, 50*time.Millisecond)}); err != nil {
+		return err
+	}
+	return d.Print()
+}
+
+// units converts d to a count of unit, clamped to 1-255.
+func units(d, unit time.Duration) byte {
+	return byte(min(max(d/unit, 1), 255))
+}
@@ -0,0 +1,74 @@
+package fax
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/png" // register the PNG decoder
+	"os"
+
+	_ "golang.org/x/image/bmp" // register the BMP decoder
+	"golang.org/x/image/draw"
+)
+
+// RasterPrinter is implemented by printers that accept raw ESC/POS commands,
+// which is how raster images are printed.
+type RasterPrinter interface {
+	WriteRaw(data []byte) (int, error)
+}
+
+// LoadRasterImage reads a PNG or BMP image and converts it to an ESC/POS
+// raster bit image command (GS v 0). Images wider than maxWidth dots are
+// scaled down to fit.
+func LoadRasterImage(path string, maxWidth int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+
+	b := img.Bounds()
+	if maxWidth > 0 && b.Dx() > maxWidth {
+		h := max(b.Dy()*maxWidth/b.Dx(), 1)
+		scaled := image.NewRGBA(image.Rect(0, 0, maxWidth, h))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, b, draw.Over, nil)
+		img = scaled
+	}
+
+	return rasterCommand(img), nil
+}
+
+// rasterCommand encodes img as a GS v 0 command, one bit per dot. Dark pixels
+// are printed; transparent ones are treated as white paper.
+func rasterCommand(img image.Image) []byte {
+	b := img.Bounds()
+	widthBytes := (b.Dx() + 7) / 8
+	height := b.Dy()
+
+	cmd := []byte{0x1d, 'v', '0', 0,
+		byte(widthBytes), byte(widthBytes >> 8),
+		byte(height), byte(height >> 8),
+	}
+	data := make([]byte, widthBytes*height)
+	for y := range height {
+		for x := range b.Dx() {
+			r, g, bl, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			// Composite onto white before thresholding.
+			gray := color.GrayModel.Convert(color.RGBA64{
+				R: uint16(r + (0xffff - a)),
+				G: uint16(g + (0xffff - a)),
+				B: uint16(bl + (0xffff - a)),
+				A: 0xffff,
+			}).(color.Gray)
+			if gray.Y < 128 {
+				data[y*widthBytes+x/8] |= 0x80 >> (x % 8)
+			}
+		}
+	}
+	return append(cmd, data...)
+}
@@ -6,6 +6,32 @@ import (
 	"github.com/securityguy/escpos"
 )
 
+// Printer is the set of printer operations used to print receipts.
+// *escpos.Escpos satisfies it.
+type Printer interface {
+	Write(data string) (int, error)
+	LineFeed() (int, error)
+	PrintAndCut() error
+}
+
+// QRCodePrinter is implemented by printers that can print QR codes.
+type QRCodePrinter interface {
+	QRCode(code string, model bool, size uint8, correctionLevel uint8) (int, error)
+}
+
+// Emphasizer is implemented by printers that can print text bold at double
+// width and height.
+type Emphasizer interface {
+	// Emphasize switches emphasis on or off for the text written after it.
+	Emphasize(on bool)
+}
+
+// PartialCutter is implemented by printers whose cutter can leave a small
+// uncut strip so receipts stay attached until torn off.
+type PartialCutter interface {
+	PrintAndPartialCut() error
+}
+
 func NewPrinter(devicePath string) (*escpos.Escpos, error) {
 	file, err := os.OpenFile(devicePath, os.O_RDWR, 0)
 	if err != nil {
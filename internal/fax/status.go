@@ -0,0 +1,132 @@
+package fax
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrStatusUnsupported is returned by Status if the printer doesn't answer
+// status queries, e.g. because the device file is write-only.
+var ErrStatusUnsupported = errors.New("printer does not report its status")
+
+// statusTimeout is how long to wait for the reply to a status query.
+const statusTimeout = 500 * time.Millisecond
+
+// StatusChecker is implemented by printers that can report their hardware
+// state.
+type StatusChecker interface {
+	Status() (Status, error)
+}
+
+// Status is the hardware state reported by a printer.
+type Status struct {
+	PaperOut    bool
+	PaperLow    bool
+	CoverOpen   bool
+	CutterError bool
+	OtherError  bool // unrecoverable or auto-recoverable error
+}
+
+// OK reports whether the printer can print. A low paper warning alone
+// doesn't stop printing.
+func (s Status) OK() bool {
+	return !s.PaperOut && !s.CoverOpen && !s.CutterError && !s.OtherError
+}
+
+// String lists the problems in s, or "ok".
+func (s Status) String() string {
+	var problems []string
+	for _, p := range []struct {
+		set  bool
+		name string
+	}{
+		{s.PaperOut, "paper out"},
+		{s.CoverOpen, "cover open"},
+		{s.CutterError, "cutter error"},
+		{s.OtherError, "printer error"},
+		{s.PaperLow, "paper low"},
+	} {
+		if p.set {
+			problems = append(problems, p.name)
+		}
+	}
+	if len(problems) == 0 {
+		return "ok"
+	}
+	return strings.Join(problems, ", ")
+}
+
+// Status queries the printer with DLE EOT for its offline cause, error cause
+// and paper sensor state. If the printer doesn't reply in time, this and all
+// later calls until Reopen return ErrStatusUnsupported.
+func (d *Device) Status() (Status, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.noStatus {
+		return Status{}, ErrStatusUnsupported
+	}
+
+	var replies [3]byte
+	for i, n := range []byte{2, 3, 4} {
+		b, err := d.queryStatus(n)
+		if err != nil {
+			return Status{}, err
+		}
+		replies[i] = b
+	}
+
+	offline, errCause, paper := replies[0], replies[1], replies[2]
+	return Status{
+		PaperOut:    offline&0x20 != 0 || paper&0x60 != 0,
+		PaperLow:    paper&0x0c != 0,
+		CoverOpen:   offline&0x04 != 0,
+		CutterError: errCause&0x08 != 0,
+		OtherError:  errCause&0x60 != 0,
+	}, nil
+}
+
+// queryStatus sends DLE EOT n and returns the status byte. d.mu must be held.
+func (d *Device) queryStatus(n byte) (byte, error) {
+	if _, err := d.file.Write([]byte{0x10, 0x04, n}); err != nil {
+		return 0, err
+	}
+
+	// Device files often can't take a read deadline, so read in the
+	// background and give up after statusTimeout. The reader is left blocked
+	// until the file is closed, which is why status queries are then turned
+	// off.
+	type reply struct {
+		b   byte
+		err error
+	}
+	replies := make(chan reply, 1)
+	file := d.file
+	go func() {
+		var buf [1]byte
+		_, err := file.Read(buf[:])
+		replies <- reply{buf[0], err}
+	}()
+
+	select {
+	case r := <-replies:
+		if errors.Is(r.err, io.EOF) {
+			d.noStatus = true
+			return 0, ErrStatusUnsupported
+		}
+		if r.err != nil {
+			return 0, r.err
+		}
+		// Every status byte has bits 1 and 4 set and bits 0 and 7 clear.
+		if r.b&0x93 != 0x12 {
+			return 0, fmt.Errorf("unexpected status byte %#02x", r.b)
+		}
+		return r.b, nil
+	case <-time.After(statusTimeout):
+		d.noStatus = true
+		return 0, ErrStatusUnsupported
+	}
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	TipsReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tipfax_tips_received_total",
+		Help: "Tips received from StreamElements.",
+	}, []string{"provider", "currency"})
+
+	TipAmount = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tipfax_tip_amount",
+		Help:    "Amount of received tips in their original currency.",
+		Buckets: []float64{1, 2, 5, 10, 20, 50, 100, 250, 500, 1000},
+	}, []string{"currency"})
+
+	ConnectionUp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "tipfax_connection_up",
+		Help: "Whether the Astro WebSocket connection is open (1) or not (0).",
+	})
+
+	Reconnects = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tipfax_reconnects_total",
+		Help: "Attempts to reconnect to Astro after the connection dropped.",
+	})
+
+	PrintErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tipfax_print_errors_total",
+		Help: "Receipts that failed to print.",
+	})
+
+	PrintQueueWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tipfax_print_queue_wait_seconds",
+		Help:    "Time from receiving a tip, or approving a moderated one, until its receipt started printing.",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300},
+	}, []string{"printer"})
+
+	PrintDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tipfax_print_duration_seconds",
+		Help:    "Time the printer took to print and cut a tip receipt.",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10},
+	}, []string{"printer"})
+
+	TimeToPrint = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tipfax_time_to_print_seconds",
+		Help:    "Queue wait plus print duration of tip receipts.",
+		Buckets: []float64{0.05, 0.1, 0.5, 1, 2, 5, 10, 30, 60, 300},
+	}, []string{"printer"})
+
+	FramesReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tipfax_frames_received_total",
+		Help: "Frames read from Astro, by type (welcome, response, message or unknown) and topic (the tip topics, other, or empty).",
+	}, []string{"type", "topic"})
+
+	MessageErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tipfax_message_errors_total",
+		Help: "Notifications from Astro that couldn't be handled, by topic and reason (parse, print or other).",
+	}, []string{"topic", "reason"})
+)
+
+// Registry holds all tipfax metrics. A dedicated registry keeps the Go runtime
+// collectors of the default one out of the output.
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(TipsReceived, TipAmount, ConnectionUp, Reconnects, PrintErrors,
+		PrintQueueWait, PrintDuration, TimeToPrint, FramesReceived, MessageErrors)
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
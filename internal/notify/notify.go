@@ -0,0 +1,51 @@
+// Package notify shows desktop notifications using the tools available on the
+// host OS.
+package notify
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// timeout bounds how long a notification command may run.
+const timeout = 5 * time.Second
+
+// Notifier shows a desktop notification.
+type Notifier interface {
+	Notify(title, body string) error
+}
+
+// New returns a notifier for the host OS. If the OS has no supported
+// notification tool installed it returns a no-op notifier and false.
+func New() (Notifier, bool) {
+	if n := newPlatform(); n != nil {
+		return n, true
+	}
+	return Nop{}, false
+}
+
+// Nop discards notifications.
+type Nop struct{}
+
+func (Nop) Notify(title, body string) error { return nil }
+
+// command runs an external notification tool.
+type command struct {
+	name string
+	args func(title, body string) []string
+}
+
+func (c command) Notify(title, body string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return exec.CommandContext(ctx, c.name, c.args(title, body)...).Run()
+}
+
+// lookCommand returns c if its tool is on PATH, and nil otherwise.
+func lookCommand(c command) Notifier {
+	if _, err := exec.LookPath(c.name); err != nil {
+		return nil
+	}
+	return c
+}
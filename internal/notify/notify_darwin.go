@@ -0,0 +1,23 @@
+package notify
+
+import "strconv"
+
+func newPlatform() Notifier {
+	if n := lookCommand(command{
+		name: "terminal-notifier",
+		args: func(title, body string) []string {
+			return []string{"-title", title, "-message", body}
+		},
+	}); n != nil {
+		return n
+	}
+
+	return lookCommand(command{
+		name: "osascript",
+		args: func(title, body string) []string {
+			// strconv.Quote escapes quotes and backslashes the way AppleScript
+			// string literals expect for the text we pass.
+			return []string{"-e", "display notification " + strconv.Quote(body) + " with title " + strconv.Quote(title)}
+		},
+	})
+}
@@ -0,0 +1,10 @@
+package notify
+
+func newPlatform() Notifier {
+	return lookCommand(command{
+		name: "notify-send",
+		args: func(title, body string) []string {
+			return []string{"--app-name=TipFax", "--", title, body}
+		},
+	})
+}
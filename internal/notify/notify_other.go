@@ -0,0 +1,7 @@
+//go:build !linux && !darwin && !windows
+
+package notify
+
+func newPlatform() Notifier {
+	return nil
+}
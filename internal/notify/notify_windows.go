@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"context"
+	"os"
+	"os/exec"
+)
+
+// toastScript shows a toast through the WinRT notification API. The title and
+// body are read from the environment so they never need escaping into the
+// script.
+const toastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName('text')
+$text.Item(0).AppendChild($xml.CreateTextNode($env:TIPFAX_TITLE)) | Out-Null
+$text.Item(1).AppendChild($xml.CreateTextNode($env:TIPFAX_BODY)) | Out-Null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($xml)
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('TipFax').Show($toast)
+`
+
+type toast struct {
+	powershell string
+}
+
+func newPlatform() Notifier {
+	path, err := exec.LookPath("powershell")
+	if err != nil {
+		return nil
+	}
+	return toast{powershell: path}
+}
+
+func (t toast) Notify(title, body string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, t.powershell, "-NoProfile", "-NonInteractive", "-Command", toastScript)
+	cmd.Env = append(os.Environ(), "TIPFAX_TITLE="+title, "TIPFAX_BODY="+body)
+	return cmd.Run()
+}
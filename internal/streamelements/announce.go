@@ -0,0 +1,61 @@
+package streamelements
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/DaniruKun/tipfax/internal/chat"
+)
+
+// announcement holds the fields available to TTS_COMMAND.
+type announcement struct {
+	Username string
+	Amount   string
+	Currency string
+	Message  string // empty unless TTS_READ_MESSAGE is set
+	Text     string // a ready-made sentence
+}
+
+// chatMessageLen is the most message runes posted to chat platforms.
+const chatMessageLen = 1000
+
+// postChats queues an announcement of d for every configured chat platform.
+// Delivery happens in the background and failures are only logged.
+func (a *Astro) postChats(d *Donation) {
+	if len(a.chats) == 0 {
+		return
+	}
+	t := chat.Tip{
+		Username: d.Username,
+		Amount:   a.formatAmount(d.Amount, d.Currency),
+		Message:  truncateRunes(a.filterMessage(d.Message), chatMessageLen),
+		Provider: d.Provider,
+		Time:     d.Timestamp,
+		TipID:    d.TipID,
+	}
+	for _, p := range a.chats {
+		p.Send(t)
+	}
+}
+
+// announceDonation queues d to be spoken through the TTS command. Failures
+// are only logged.
+func (a *Astro) announceDonation(d *Donation) {
+	ann := announcement{
+		Username: d.Username,
+		Amount:   strconv.FormatFloat(d.Amount, 'f', -1, 64),
+		Currency: d.Currency,
+	}
+	ann.Text = fmt.Sprintf("New tip from %s, %s %s", ann.Username, ann.Amount, ann.Currency)
+
+	if message := a.filterMessage(d.Message); a.cfg.TTSReadMessage && message != "" {
+		msg := []rune(message)
+		if max := a.cfg.TTSMaxMessageLength; max > 0 && len(msg) > max {
+			msg = msg[:max]
+		}
+		ann.Message = string(msg)
+		ann.Text += ". " + ann.Message
+	}
+
+	a.speaker.Announce(ann)
+}
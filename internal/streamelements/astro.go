@@ -1,15 +1,29 @@
 package streamelements
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/url"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
 
+	"github.com/DaniruKun/tipfax/internal/chat"
 	"github.com/DaniruKun/tipfax/internal/config"
+	"github.com/DaniruKun/tipfax/internal/fax"
+	"github.com/DaniruKun/tipfax/internal/metrics"
+	"github.com/DaniruKun/tipfax/internal/notify"
+	"github.com/DaniruKun/tipfax/internal/tts"
+	"github.com/DaniruKun/tipfax/internal/webhook"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
-	"github.com/securityguy/escpos"
 )
 
 const (
@@ -17,275 +31,1174 @@ const (
 	TipsModerationTopic = "channel.tips.moderation"
 )
 
+// printedWindow is how long printed tips are remembered, so a refund that
+// arrives later can be matched to its receipt.
+const printedWindow = 7 * 24 * time.Hour
+
+// ErrReconnectLimit is returned by ListenWithReconnect once
+// MaxReconnectAttempts consecutive reconnects have failed, wrapped with the
+// last failure.
+var ErrReconnectLimit = errors.New("gave up reconnecting to Astro")
+
+// Errors for exceeding CONNECT_TIMEOUT, SUBSCRIBE_TIMEOUT and READ_TIMEOUT.
+// They are wrapped with the underlying error; ListenWithReconnect treats them
+// like any other connection failure and reconnects.
+var (
+	ErrConnectTimeout   = classify(ErrConnection, errors.New("timed out connecting to Astro"))
+	ErrSubscribeTimeout = classify(ErrSubscription, errors.New("timed out waiting for Astro to answer a subscription"))
+	ErrReadTimeout      = classify(ErrConnection, errors.New("timed out waiting for data from Astro"))
+)
+
 type Message struct {
 	Type  string `json:"type"`
 	Topic string `json:"topic"`
+	Room  string `json:"room,omitempty"` // channel ID of notifications
 	Nonce string `json:"nonce"`
-	Data  any    `json:"data"`
+
+	// Data is kept raw and decoded per message type, see decodeData.
+	Data json.RawMessage `json:"data,omitempty"`
 }
 
 type Astro struct {
-	cfg     *config.Config
-	conn    *websocket.Conn
-	printer *escpos.Escpos
+	cfg          *config.Config
+	logger       *slog.Logger
+	conn         *websocket.Conn
+	reader       *connReader         // reads from conn in the background
+	stations     map[string]*station // printers by name
+	stationOrder []string            // station names in the order they were added
+	channels     []*channel          // the SE_JWT_TOKEN channel first, then CHANNEL_TOKENS
+
+	receiptTmpl   *template.Template
+	locale        locale // receipt language
+	headerImage   []byte // raster command printed above each receipt, if any
+	qrTmpl        *template.Template
+	footer        *footerPicker
+	tipLog        *TipLog
+	tipStore      *TipStore
+	webhook       *webhook.Dispatcher
+	notifier      notify.Notifier
+	speaker       *tts.Speaker
+	chats         []*chat.Poster
+	seen          *seenSet    // recently handled tips, to drop reconnect replays
+	printed       *seenSet    // IDs of recently printed tips, to flag later refunds
+	received      *seenSet    // IDs of recently received tips, to tell early moderation decisions from late ones
+	spam          *spamGuard  // nil unless SPAM_WINDOW is set
+	compact       atomic.Bool // print compact receipts, see SetCompactReceipts
+	paused        atomic.Bool // queue tips instead of printing them, see SetPaused
+	wasReady      atomic.Bool // subscribed to tips at least once, see announceReady
+	converter     *CurrencyConverter
+	codePage      *fax.CodePage // CODE_PAGE, nil to print ASCII only
+	stats         *sessionStats
+	latency       latencyStats                   // time to print, see Status.PrintLatency
+	frames        frameStats                     // frames read from Astro, see FrameStats
+	handlers      map[string]func(Message) error // notification handlers by topic
+	noPrinterOnce sync.Once                      // warns the first time a tip has no printer
+	pendingSave   sync.Mutex                     // serializes savePending
+	seenSave      sync.Mutex                     // serializes writeSeen
+
+	mu            sync.Mutex
+	subs          []subscription                  // subscriptions to restore after a reconnect
+	connected     bool                            // whether the WebSocket is open
+	subscribed    bool                            // whether a subscription has been acknowledged
+	lastMessageAt time.Time                       // when the last frame was read
+	lastCloseCode int                             // close code of the last dropped connection, 0 if none
+	pending       map[string]pendingTip           // tips awaiting approval, keyed by tip ID, saved by savePending
+	early         map[string]earlyDecision        // moderation decisions received before their tip
+	waiters       map[string]chan subscribeResult // subscribe requests awaiting a response, by nonce
+	token         string                          // JWT for subscriptions, reloaded on reconnect
+	authFailures  int                             // subscription auth errors since the last success
+	events        chan TipEvent                   // handled tips, created by Events
+	eventSubs     []chan TipEvent                 // channels publish sends handled tips to
+	state         StateChange                     // the last connection state change
+	stateSubs     []chan StateChange              // channels setState sends state changes to
+	recent        []TipEvent                      // the last recentTipsMax handled tips, oldest first
+	unknownTopics map[string]bool                 // topics without a handler already warned about
+	unitWarned    map[string]bool                 // providers missing from AMOUNT_IN_MINOR_UNITS already warned about
+	requests      map[string]request              // requests in flight by nonce, see newRequest
+	newNonce      func() string                   // makes request nonces; tests may swap it for predictable ones
+	welcomed      bool                            // whether this connection got a welcome
+	seenSaveDue   bool                            // saveSeen has scheduled a write of DEDUP_PATH
+}
+
+// Status is a snapshot of the connection state, for health checks.
+type Status struct {
+	Connected     bool      `json:"connected"`
+	Subscribed    bool      `json:"subscribed"`
+	LastMessageAt time.Time `json:"lastMessageAt"`
+	PrintQueued   int       `json:"printQueued"` // tips waiting to be printed
+	LastCloseCode int       `json:"lastCloseCode,omitempty"`
+
+	// PrinterErrors maps printers reporting a problem, e.g. paper out, to
+	// that problem.
+	PrinterErrors map[string]string `json:"printerErrors,omitempty"`
+
+	// PrintLatency is nil until a tip was printed.
+	PrintLatency *PrintLatency `json:"printLatency,omitempty"`
+
+	CompactReceipts bool `json:"compactReceipts"` // see SetCompactReceipts
+	Paused          bool `json:"paused"`          // see SetPaused
 }
 
-func NewAstro(cfg *config.Config, printer *escpos.Escpos) *Astro {
-	return &Astro{cfg: cfg, printer: printer}
+func (a *Astro) Status() Status {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return Status{
+		Connected:     a.connected,
+		Subscribed:    a.subscribed,
+		LastMessageAt: a.lastMessageAt,
+		PrintQueued:   a.queuedTips(),
+		LastCloseCode: a.lastCloseCode,
+		PrinterErrors: a.printerFaults(),
+		PrintLatency:  a.latency.snapshot(),
+
+		CompactReceipts: a.compact.Load(),
+		Paused:          a.paused.Load(),
+	}
+}
+
+// NewAstro creates an Astro client that prints tips to printer, registered as
+// the default printer. printer may be nil, in which case tips are only logged
+// unless other printers are added. A nil logger means slog.Default().
+func NewAstro(cfg *config.Config, printer fax.Printer, logger *slog.Logger) *Astro {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	a := &Astro{
+		cfg:         cfg,
+		logger:      logger,
+		receiptTmpl: parseReceiptTemplate(cfg.ReceiptTemplate, logger),
+		locale:      lookupLocale(cfg.ReceiptLanguage, logger),
+		qrTmpl:      parseQRTemplate(cfg.QRURLTemplate, logger),
+		footer:      newFooterPicker(cfg.FooterMessages, cfg.FooterMode),
+		pending:     make(map[string]pendingTip),
+		early:       make(map[string]earlyDecision),
+		waiters:     make(map[string]chan subscribeResult),
+		requests:    make(map[string]request),
+		newNonce:    func() string { return uuid.New().String() },
+		stations:    make(map[string]*station),
+		seen:        newSeenSet(cfg.DedupWindow, cfg.DedupCapacity),
+		printed:     newSeenSet(printedWindow, cfg.DedupCapacity),
+		received:    newSeenSet(cfg.PendingTipTTL, cfg.DedupCapacity),
+		converter:   NewCurrencyConverter(cfg.BaseCurrency, cfg.CurrencyRates),
+		stats:       newSessionStats(),
+		token:       cfg.SeJWTToken,
+	}
+	a.spam = newSpamGuard(cfg.SpamWindow, cfg.SpamMaxIdentical, a.flushSpam)
+	a.compact.Store(cfg.CompactReceipt)
+	a.codePage, _ = fax.LookupCodePage(cfg.CodePage)
+	a.loadPending()
+	a.loadSeen()
+	a.registerHandlers()
+	a.setupChannels()
+
+	if printer != nil {
+		a.AddPrinter(cfg.DefaultPrinter, printer)
+	}
+
+	if cfg.HeaderImagePath != "" {
+		img, err := fax.LoadRasterImage(cfg.HeaderImagePath, cfg.PrinterDotWidth)
+		if err != nil {
+			logger.Warn("failed to load header image, printing text only", "path", cfg.HeaderImagePath, "error", err)
+		} else {
+			a.headerImage = img
+		}
+	}
+
+	if cfg.TipLogPath != "" {
+		tipLog, err := OpenTipLog(cfg.TipLogPath)
+		if err != nil {
+			logger.Warn("failed to open tip log, tips won't be recorded", "path", cfg.TipLogPath, "error", err)
+		} else {
+			a.tipLog = tipLog
+		}
+	}
+
+	if cfg.TipDBPath != "" {
+		tipStore, err := OpenTipStore(cfg.TipDBPath, logger)
+		if err != nil {
+			logger.Warn("failed to open tip database, tips won't be stored", "path", cfg.TipDBPath, "error", err)
+		} else {
+			a.tipStore = tipStore
+		}
+	}
+
+	if cfg.WebhookURL != "" {
+		dispatcher, err := webhook.New(cfg.WebhookURL, cfg.WebhookSecret, cfg.WebhookTimeout, cfg.WebhookOutboxDir, logger)
+		if err != nil {
+			logger.Warn("failed to set up webhook, tips won't be forwarded", "error", err)
+		} else {
+			a.webhook = dispatcher
+		}
+	}
+
+	if cfg.DiscordWebhookURL != "" {
+		a.chats = append(a.chats, chat.New(cfg.DiscordWebhookURL, chat.Discord{}, cfg.WebhookTimeout, logger))
+	}
+	if cfg.SlackWebhookURL != "" {
+		a.chats = append(a.chats, chat.New(cfg.SlackWebhookURL, chat.Slack{}, cfg.WebhookTimeout, logger))
+	}
+
+	if cfg.TTS {
+		speaker, err := tts.New(cfg.TTSCommand, logger)
+		if err != nil {
+			logger.Warn("invalid TTS_COMMAND, tips won't be announced", "error", err)
+		} else {
+			a.speaker = speaker
+		}
+	}
+
+	if cfg.DesktopNotifications {
+		n, ok := notify.New()
+		if !ok {
+			logger.Warn("no desktop notification tool found, notifications disabled")
+		}
+		a.notifier = n
+	}
+
+	return a
 }
 
 func (a *Astro) Connect() error {
-	u := url.URL{Scheme: "wss", Host: "astro.streamelements.com", Path: "/"}
-	log.Printf("Connecting to %s", u.String())
+	u, err := url.Parse(a.cfg.AstroURL)
+	if err != nil {
+		return fmt.Errorf("invalid Astro URL %q: %w", a.cfg.AstroURL, err)
+	}
+	if (u.Scheme != "ws" && u.Scheme != "wss") || u.Host == "" {
+		return fmt.Errorf("invalid Astro URL %q: expected a ws:// or wss:// URL", a.cfg.AstroURL)
+	}
+	a.logger.Info("connecting to Astro", "url", u.String())
+	a.setState(StateConnecting, "")
 
-	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	dialer, err := newDialer(a.cfg)
 	if err != nil {
-		log.Fatal("Error connecting:", err)
+		return err
+	}
+	if a.cfg.TLSInsecureSkipVerify {
+		a.logger.Warn("TLS certificate verification is disabled")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.cfg.ConnectTimeout)
+	conn, _, err := dialer.DialContext(ctx, u.String(), nil)
+	cancel()
+	if err != nil {
+		if isTimeout(err) {
+			err = fmt.Errorf("error connecting to %s: %w after %s: %w", u.String(), ErrConnectTimeout, a.cfg.ConnectTimeout, err)
+		} else {
+			err = classify(ErrConnection, fmt.Errorf("error connecting to %s: %w", u.String(), err))
+		}
+		a.setState(StateDisconnected, err.Error())
+		return err
 	}
-	log.Println("Connected to Astro")
+	a.logger.Info("connected to Astro")
+
+	// Astro drops idle connections, so ping periodically and treat a missing
+	// pong as a dead connection by letting the read deadline expire. The
+	// reader extends the deadline after every message.
+	conn.SetReadDeadline(time.Now().Add(a.cfg.ReadTimeout))
+	conn.SetPongHandler(func(string) error {
+		a.mu.Lock()
+		a.lastMessageAt = time.Now()
+		a.mu.Unlock()
+		return conn.SetReadDeadline(time.Now().Add(a.cfg.ReadTimeout))
+	})
+	conn.SetCloseHandler(func(code int, text string) error {
+		a.logger.Info("Astro sent a close frame", "code", code, "reason", text)
+		// Echo the close frame, as the default handler does.
+		msg := websocket.FormatCloseMessage(code, "")
+		conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+		return nil
+	})
+	go keepalive(conn, a.cfg.PingInterval)
+
+	reader := a.startReader(conn, a.cfg.ReadTimeout)
 
+	a.mu.Lock()
 	a.conn = conn
+	a.reader = reader
+	a.welcomed = false
+	a.connected = true
+	a.lastMessageAt = time.Now()
+	a.mu.Unlock()
+	metrics.ConnectionUp.Set(1)
+	a.setState(StateConnected, "")
 
 	return nil
 }
 
-func (a *Astro) SubscribeTips() error {
+// keepalive pings conn every interval until a ping can no longer be written,
+// which happens once the connection is closed.
+func keepalive(conn *websocket.Conn, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deadline := time.Now().Add(10 * time.Second)
+		if err := conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+			return
+		}
+	}
+}
+
+// SubscribeTips subscribes to tips on every channel and waits, up to ctx's
+// deadline, for Astro to accept or reject each subscription. It only fails if
+// no channel could be subscribed.
+func (a *Astro) SubscribeTips(ctx context.Context) error {
+	if err := a.subscribeChannels(ctx, TipsTopic); err != nil {
+		return err
+	}
+	a.announceReady()
+	return nil
+}
+
+// subscribedTo reports whether a subscription to topic was accepted on any
+// channel.
+func (a *Astro) subscribedTo(topic string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return slices.ContainsFunc(a.subs, func(s subscription) bool { return s.topic == topic })
+}
+
+// SubscribeModeration subscribes to approve/deny decisions for moderated tips
+// and waits for Astro's response like SubscribeTips.
+func (a *Astro) SubscribeModeration(ctx context.Context) error {
+	return a.subscribeChannels(ctx, TipsModerationTopic)
+}
+
+// jwt returns the token used for subscriptions.
+func (a *Astro) jwt() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.token
+}
+
+func (a *Astro) checkToken() error {
 	// Validate token
-	if a.cfg.SeJWTToken == "" {
-		return fmt.Errorf("SE_JWT_TOKEN is empty or not set")
+	token := a.jwt()
+	if token == "" {
+		return classify(ErrAuth, errors.New("SE_JWT_TOKEN is empty or not set"))
 	}
 
-	// Basic JWT validation (should have 3 parts separated by dots)
-	parts := len(a.cfg.SeJWTToken)
-	if parts < 50 {
-		log.Printf("⚠️  Warning: JWT token seems unusually short (%d chars). This might be invalid.", parts)
+	if err := config.ValidateJWT(token); err != nil {
+		return classify(ErrAuth, fmt.Errorf("SE_JWT_TOKEN is not a valid JWT: %w", err))
 	}
 
-	nonce := uuid.New().String()
+	return nil
+}
+
+// subscribe sends a subscribe message for topic on ch and waits for the
+// response with the same nonce, resending it once if none arrives within
+// SubscribeTimeout. Accepted subscriptions are remembered so they can be
+// restored after a reconnect.
+func (a *Astro) subscribe(ctx context.Context, topic string, ch *channel) error {
+	// A subscribe request Astro dropped is never acknowledged; responses with
+	// other nonces don't count. Send it once more before giving up.
+	room, err := a.sendSubscribe(ctx, topic, ch)
+	if errors.Is(err, ErrSubscribeTimeout) && ctx.Err() == nil {
+		a.logger.Warn("no acknowledgement for subscription, sending it again", "topic", topic, "channel", ch.name)
+		room, err = a.sendSubscribe(ctx, topic, ch)
+	}
+	if err != nil {
+		return fmt.Errorf("subscribe to %s: %w", topic, err)
+	}
+
+	a.mu.Lock()
+	if room != "" {
+		ch.room = room
+	}
+	sub := subscription{topic: topic, channel: ch}
+	if !slices.Contains(a.subs, sub) {
+		a.subs = append(a.subs, sub)
+	}
+	a.mu.Unlock()
+
+	return nil
+}
+
+// sendSubscribe sends one subscribe request for topic on ch with a new nonce
+// and waits up to SubscribeTimeout for the response carrying that nonce. It
+// returns the subscribed room.
+func (a *Astro) sendSubscribe(ctx context.Context, topic string, ch *channel) (string, error) {
+	nonce, err := a.newRequest("subscribe", topic)
+	if err != nil {
+		return "", err
+	}
+	token := a.topicToken(topic, ch)
 	subscribeMessage := map[string]any{
 		"type":  "subscribe",
 		"nonce": nonce,
 		"data": map[string]any{
-			"topic":      TipsTopic,
-			"token":      a.cfg.SeJWTToken,
+			"topic":      topic,
+			"token":      token,
 			"token_type": "jwt",
 		},
 	}
 
-	// Log subscription attempt (mask token for security)
-	tokenPreview := a.cfg.SeJWTToken
-	if len(tokenPreview) > 20 {
-		tokenPreview = tokenPreview[:10] + "..." + tokenPreview[len(tokenPreview)-10:]
-	}
-	log.Printf("Subscribing to topic '%s' with nonce '%s' (token length: %d, preview: %s)",
-		TipsTopic, nonce, len(a.cfg.SeJWTToken), tokenPreview)
-	log.Printf("Subscription message: type=%s, nonce=%s, topic=%s", subscribeMessage["type"], nonce, TipsTopic)
+	a.logger.Info("subscribing", "topic", topic, "channel", ch.name, "nonce", nonce,
+		"token_length", len(token), "token_preview", maskToken(token))
+	a.logger.Debug("subscription message", "message", a.redact(subscribeMessage))
 
+	wait := a.awaitResponse(nonce)
 	if err := a.conn.WriteJSON(subscribeMessage); err != nil {
-		log.Printf("Error sending subscription message: %v", err)
-		return err
+		a.logger.Error("failed to send subscription message", "topic", topic, "nonce", nonce, "error", err)
+		a.dropWaiter(nonce)
+		return "", classify(ErrConnection, err)
 	}
 
-	log.Println("Subscription message sent, waiting for response...")
-
-	return nil
+	a.logger.Debug("subscription message sent, waiting for response", "topic", topic, "nonce", nonce)
+	ctx, cancel := context.WithTimeout(ctx, a.cfg.SubscribeTimeout)
+	defer cancel()
+	return wait(ctx)
 }
 
-func (a *Astro) Listen() error {
-	for {
-		var msg Message
-		err := a.conn.ReadJSON(&msg)
-		if err != nil {
-			log.Println("Error reading message:", err)
-			return err
-		}
-
-		log.Printf("Received message: %+v", msg)
-
-		// Handle different message types
-		switch msg.Type {
-		case "welcome":
-			if welcomeData, ok := msg.Data.(map[string]any); ok {
-				if clientID, ok := welcomeData["client_id"].(string); ok {
-					log.Printf("✅ Connected to Astro (client_id: %s)", clientID)
-				}
-				if welcomeMsg, ok := welcomeData["message"].(string); ok {
-					log.Printf("   %s", welcomeMsg)
-				}
-			}
-		case "response":
-			log.Printf("Received response: Type=%s, Nonce=%s", msg.Type, msg.Nonce)
-
-			// Parse response data
-			if responseData, ok := msg.Data.(map[string]any); ok {
-				responseMsg, hasMessage := responseData["message"].(string)
-
-				// Check if this is an error or success
-				isError := false
-				if hasMessage {
-					// Convert to lowercase for case-insensitive matching
-					lowerMsg := strings.ToLower(responseMsg)
-
-					// Check for success indicators first
-					successKeywords := []string{"success", "subscribed"}
-					hasSuccess := false
-					for _, keyword := range successKeywords {
-						if strings.Contains(lowerMsg, keyword) {
-							hasSuccess = true
-							break
-						}
-					}
-
-					// If not a success message, check for error indicators
-					if !hasSuccess {
-						errorKeywords := []string{"error", "failed", "invalid", "unauthorized", "forbidden", "not found"}
-						for _, keyword := range errorKeywords {
-							if strings.Contains(lowerMsg, keyword) {
-								isError = true
-								break
-							}
-						}
-					}
-
-					// Also check for error code or error type fields
-					if _, hasErrorCode := responseData["code"]; hasErrorCode {
-						isError = true
-					}
-					if errorType, ok := responseData["type"].(string); ok {
-						if errorType == "error" {
-							isError = true
-						}
-					}
-				}
+// Listen reads and handles messages until the connection fails or ctx is
+// cancelled. On cancellation it unsubscribes from every active topic and
+// disconnects before returning ctx.Err().
+func (a *Astro) Listen(ctx context.Context) error {
+	a.mu.Lock()
+	msgs, errs := a.reader.msgs, a.reader.errs
+	a.mu.Unlock()
 
-				if isError {
-					log.Printf("❌ Error response: %s", responseMsg)
-					// Check for additional error details
-					if errorCode, ok := responseData["code"].(string); ok {
-						log.Printf("   Error code: %s", errorCode)
-					}
-					if errorType, ok := responseData["type"].(string); ok {
-						log.Printf("   Error type: %s", errorType)
-					}
-					log.Printf("   Full response data: %+v", responseData)
-				} else {
-					// Success response
-					if hasMessage {
-						log.Printf("✅ %s", responseMsg)
-					} else {
-						log.Printf("✅ Success response: %+v", responseData)
-					}
-
-					// Log topic info if present
-					if topic, ok := responseData["topic"].(string); ok {
-						log.Printf("   Topic: %s", topic)
-					}
-					if room, ok := responseData["room"].(string); ok {
-						log.Printf("   Room: %s", room)
-					}
-				}
-			} else {
-				log.Printf("Response data (raw): %+v", msg.Data)
+	for {
+		select {
+		case <-ctx.Done():
+			// Don't hold up shutdown for long waiting on acknowledgements.
+			unsubCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			a.mu.Lock()
+			subs := slices.Clone(a.subs)
+			a.mu.Unlock()
+			if err := a.unsubscribeAll(unsubCtx, subs); err != nil {
+				a.logger.Warn("failed to unsubscribe cleanly", "error", err)
 			}
-		case "message":
-			log.Println("Received notification:", msg)
-			// Process the notification based on the topic
-			if msg.Topic == TipsTopic {
-				a.handleTipMessage(msg)
+			cancel()
+			a.Disconnect()
+			return ctx.Err()
+		case err := <-errs:
+			code := websocket.CloseAbnormalClosure
+			var closeErr *websocket.CloseError
+			switch {
+			case errors.As(err, &closeErr) && isNormalClose(err):
+				code = closeErr.Code
+				a.logger.Info("Astro closed the connection", "code", code, "reason", closeErr.Text)
+			case errors.As(err, &closeErr):
+				code = closeErr.Code
+				a.logger.Warn("connection closed abnormally", "code", code, "reason", closeErr.Text)
+			default:
+				a.logger.Error("failed to read message", "error", err)
 			}
-		default:
-			log.Printf("Received unknown message type '%s': %+v", msg.Type, msg)
+
+			a.mu.Lock()
+			a.connected = false
+			a.lastCloseCode = code
+			a.mu.Unlock()
+			metrics.ConnectionUp.Set(0)
+			a.setState(StateDisconnected, err.Error())
+			return classify(ErrConnection, err)
+		case msg := <-msgs:
+			a.mu.Lock()
+			a.lastMessageAt = time.Now()
+			a.mu.Unlock()
+
+			a.handleMessage(msg)
 		}
 	}
 }
 
-func (a *Astro) handleTipMessage(msg Message) {
-	log.Println("🎉 NEW TIP RECEIVED! 🎉")
+func (a *Astro) handleMessage(msg Message) {
+	newUnknown := a.frames.record(msg)
 
-	// Parse the tip data
-	data, ok := msg.Data.(map[string]any)
-	if !ok {
-		log.Println("Error parsing tip data")
-		return
+	if a.cfg.DebugRawMessages {
+		a.logger.Info("received message", "type", msg.Type, "topic", msg.Topic, "room", msg.Room, "nonce", msg.Nonce, "data", a.redact(msg.Data))
+	} else {
+		a.logger.Debug("received message", "type", msg.Type, "topic", msg.Topic)
 	}
 
-	if donation, ok := data["donation"].(map[string]any); ok {
-		username := "Unknown"
-		amount := "0"
-		currency := "USD"
-		message := ""
+	// Handle different message types
+	switch msg.Type {
+	case "welcome":
+		a.mu.Lock()
+		again := a.welcomed
+		a.welcomed = true
+		a.mu.Unlock()
 
-		if user, ok := donation["user"].(map[string]any); ok {
-			if name, ok := user["username"].(string); ok {
-				username = name
-			}
+		var welcome struct {
+			ClientID string `json:"client_id"`
+			Message  string `json:"message"`
+		}
+		if err := decodeData(msg, &welcome); err != nil {
+			a.logBadData(msg, err)
+		}
+		if again {
+			// Astro may reconnect on its side without closing ours.
+			a.logger.Info("welcomed by Astro again on the same connection", "client_id", welcome.ClientID, "message", welcome.Message)
+		} else {
+			a.logger.Info("welcomed by Astro", "client_id", welcome.ClientID, "message", welcome.Message)
+		}
+	case "response":
+		a.logger.Debug("received response", "nonce", msg.Nonce)
+		req, known := a.takeRequest(msg.Nonce)
+		if !known {
+			a.logger.Warn("response with unknown nonce, possibly for a request that timed out or was sent on an earlier connection",
+				"nonce", msg.Nonce, "data", a.redact(msg.Data))
 		}
 
-		if amt, ok := donation["amount"].(float64); ok {
-			amount = fmt.Sprintf("%.2f", amt)
+		var responseData map[string]any
+		if err := decodeData(msg, &responseData); err != nil {
+			a.logBadData(msg, err)
+			break
 		}
-		if curr, ok := donation["currency"].(string); ok {
-			currency = curr
+
+		success, responseMsg := classifyResponse(responseData)
+		a.mu.Lock()
+		// A moderation token without the scope says nothing about whether
+		// the tips token has expired.
+		switch {
+		case req.topic == TipsModerationTopic:
+		case !success && isAuthError(responseMsg):
+			a.authFailures++
+		case success:
+			a.authFailures = 0
+		}
+		a.mu.Unlock()
+		if !success {
+			errorCode, _ := responseData["code"].(string)
+			errorType, _ := responseData["type"].(string)
+			a.logger.Error("error response", "nonce", msg.Nonce, "message", responseMsg,
+				"code", errorCode, "error_type", errorType, "data", a.redact(responseData))
+			break
 		}
 
-		if msg, ok := donation["message"].(string); ok {
-			message = msg
+		// Only an answer to one of our subscribe requests means we are
+		// subscribed.
+		if known && req.kind == "subscribe" {
+			a.mu.Lock()
+			a.subscribed = true
+			a.mu.Unlock()
+			a.setState(StateSubscribed, "")
 		}
 
-		status := "unknown"
-		provider := "unknown"
-		if statusVal, ok := data["status"].(string); ok {
-			status = statusVal
+		topic, _ := responseData["topic"].(string)
+		room, _ := responseData["room"].(string)
+		if responseMsg != "" {
+			a.logger.Info(responseMsg, "nonce", msg.Nonce, "topic", topic, "room", room)
+		} else {
+			a.logger.Info("success response", "nonce", msg.Nonce, "topic", topic, "room", room, "data", a.redact(responseData))
 		}
-		if providerVal, ok := data["provider"].(string); ok {
-			provider = providerVal
+	case "message":
+		a.logger.Debug("received notification", "topic", msg.Topic)
+		a.dispatch(msg)
+	default:
+		a.logger.Warn("received unknown message type", "type", msg.Type, "topic", msg.Topic, "first_seen", newUnknown, "data", a.redact(msg.Data))
+	}
+}
+
+// registerHandlers sets up the handler for each topic tipfax subscribes to.
+func (a *Astro) registerHandlers() {
+	a.handlers = map[string]func(Message) error{
+		TipsTopic:           a.handleTipMessage,
+		TipsModerationTopic: a.handleModerationMessage,
+	}
+}
+
+// dispatch passes a notification to the handler for its topic. Topics
+// without a handler are logged once.
+func (a *Astro) dispatch(msg Message) {
+	if handler, ok := a.handlers[msg.Topic]; ok {
+		if err := handler(msg); err != nil {
+			a.handlerFailed(msg, err)
 		}
+		return
+	}
 
-		log.Printf("💰 Tip from %s: %s %s (via %s)", username, amount, currency, provider)
-		log.Printf("📊 Status: %s", status)
-		if message != "" {
-			log.Printf("💬 Message: %s", message)
+	a.mu.Lock()
+	warned := a.unknownTopics[msg.Topic]
+	if !warned {
+		if a.unknownTopics == nil {
+			a.unknownTopics = make(map[string]bool)
 		}
+		a.unknownTopics[msg.Topic] = true
+	}
+	a.mu.Unlock()
+	if !warned {
+		a.logger.Warn("no handler for topic, ignoring its messages", "topic", msg.Topic)
+	}
+}
 
-		// Print to thermal printer if available
-		if a.printer != nil {
-			a.printer.Write(fmt.Sprintf("Tip from %s: %s %s", username, amount, currency))
-			a.printer.LineFeed()
-			a.printer.Write(fmt.Sprintf("Status: %s", status))
-			a.printer.LineFeed()
-			if message != "" {
-				a.printer.Write(fmt.Sprintf("Message: %s", message))
-				a.printer.LineFeed()
-			}
-			a.printer.PrintAndCut()
+// handlerFailed counts and logs an error returned by a topic handler. Tips
+// that failed to print were already logged and queued by printOn.
+func (a *Astro) handlerFailed(msg Message, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidMessage):
+		metrics.MessageErrors.WithLabelValues(msg.Topic, "parse").Inc()
+		a.logger.Error("failed to parse message", "topic", msg.Topic, "error", err, "raw", a.redact(msg.Data))
+	case errors.Is(err, ErrPrinter):
+		metrics.MessageErrors.WithLabelValues(msg.Topic, "print").Inc()
+		a.logger.Debug("tip not printed right away", "topic", msg.Topic, "error", err)
+	default:
+		metrics.MessageErrors.WithLabelValues(msg.Topic, "other").Inc()
+		a.logger.Error("failed to handle message", "topic", msg.Topic, "error", err)
+	}
+}
+
+// handleTipMessage handles a channel.tips notification. It returns an
+// ErrInvalidMessage error if the tip can't be decoded, and an ErrPrinter one
+// if it couldn't be printed, in which case it is queued.
+func (a *Astro) handleTipMessage(msg Message) error {
+	d, err := ParseDonation(msg.Data, a.logger)
+	if err != nil {
+		return classify(ErrInvalidMessage, fmt.Errorf("parse tip data: %w", err))
+	}
+	if d.Currency == "" {
+		d.Currency = a.defaultCurrency()
+	}
+	a.normalizeAmountUnit(d)
+
+	if a.seen.Seen(dedupKey(d), time.Now()) {
+		a.logger.Debug("skipping duplicate tip", "tip_id", d.TipID, "username", d.Username)
+		return nil
+	}
+	a.saveSeen()
+
+	if d.TipID != "" {
+		a.received.Seen(d.TipID, time.Now())
+		if action, ok := a.takeDecision(d.TipID); ok {
+			a.logger.Info("applying moderation decision received before the tip", "tip_id", d.TipID, "action", action)
+			d.Moderation = action
 		}
-	} else {
-		log.Println("Error: Could not find donation data in tip message")
-		log.Printf("Raw data: %+v", data)
 	}
+
+	d.Channel = a.channelForRoom(msg.Room)
+	a.convertDonation(d)
+	ev := NewTipEvent(msg.Topic, d, time.Now())
+
+	if a.tipLog != nil {
+		if err := a.tipLog.Append(ev); err != nil {
+			a.logger.Warn("failed to write tip log", "tip_id", d.TipID, "error", err)
+		}
+	}
+	if a.tipStore != nil {
+		a.tipStore.Save(d)
+	}
+	if a.webhook != nil {
+		a.webhook.Send(d.TipID, ev)
+	}
+
+	return a.handleDonation(ev)
+}
+
+// handleDonation logs the tip in ev and prints it unless it is filtered out.
+// It is shared by live tips and replayed ones. Only printing errors are
+// returned; filtered tips are not an error. Callers convert the tip to the
+// base currency first.
+func (a *Astro) handleDonation(ev TipEvent) error {
+	d := ev.Donation
+	if d.receivedAt.IsZero() {
+		d.receivedAt = time.Now()
+	}
+	metrics.TipsReceived.WithLabelValues(d.Provider, d.Currency).Inc()
+	metrics.TipAmount.WithLabelValues(d.Currency).Observe(d.Amount)
+
+	a.logger.Info("tip received", "tip_id", d.TipID, "username", d.Username, "amount", d.Amount,
+		"currency", d.Currency, "formatted", a.formatAmount(d.Amount, d.Currency),
+		"provider", d.Provider, "status", d.Status, "channel", d.Channel, "message", d.Message)
+
+	if d.Status.Reversed() {
+		a.handleReversal(d)
+		return nil
+	}
+	// Pending and denied tips stay out of stats, chat and announcements until
+	// a moderator lets them through. Approved tips print whatever their
+	// status. Pending tips are held for a decision with PRINT_ONLY_APPROVED,
+	// and otherwise if their status isn't printable but moderation decisions
+	// can still arrive.
+	printable := a.statusPrintable(d.Status)
+	switch {
+	case a.cfg.PrintOnlyApproved && d.Moderation == ModerationDenied:
+		a.logger.Info("skipping denied tip", "tip_id", d.TipID)
+		return nil
+	case d.Pending() && (a.cfg.PrintOnlyApproved || (!printable && a.subscribedTo(TipsModerationTopic))):
+		a.holdPending(d)
+		return nil
+	case !printable && d.Moderation != ModerationApproved:
+		a.logger.Info("tip status not printable, not printing", "tip_id", d.TipID, "status", d.Status)
+		return nil
+	}
+
+	return a.deliverDonation(ev)
+}
+
+// deliverDonation counts, announces and publishes the tip in ev, then prints
+// it unless a print filter drops it. It runs once a tip is cleared, either
+// straight from handleDonation or when a moderator approves it.
+func (a *Astro) deliverDonation(ev TipEvent) error {
+	d := ev.Donation
+	a.recordStats(d)
+
+	if a.notifier != nil {
+		go a.notifyDonation(d)
+	}
+	if a.speaker != nil {
+		a.announceDonation(d)
+	}
+	a.postChats(d)
+	a.publish(ev)
+
+	if !a.providerAllowed(d.Provider) {
+		a.logger.Info("tip provider not allowed, not printing", "tip_id", d.TipID, "provider", d.Provider)
+		return nil
+	}
+
+	if !a.currencyAllowed(d.Currency) {
+		a.logger.Info("tip currency not allowed, not printing", "tip_id", d.TipID, "currency", d.Currency)
+		return nil
+	}
+
+	if a.belowMinimum(d) {
+		a.logger.Info("tip below minimum print amount, not printing", "tip_id", d.TipID,
+			"min_amount", a.cfg.MinPrintAmount, "base_currency", a.cfg.BaseCurrency)
+		return nil
+	}
+
+	if a.spam != nil && a.spam.hold(d) {
+		a.logger.Info("repeated identical tip, collapsing it into one receipt", "tip_id", d.TipID, "username", d.Username)
+		return nil
+	}
+
+	return a.printDonation(d)
 }
 
+// notifyDonationMessageLen is the most message runes shown in a desktop
+// notification.
+const notifyDonationMessageLen = 100
+
+// notifyDonation shows a desktop notification for d. Failures are only logged.
+func (a *Astro) notifyDonation(d *Donation) {
+	title := "Tip from " + d.Username
+	body := a.formatAmount(d.Amount, d.Currency)
+	if msg := []rune(d.Message); len(msg) > notifyDonationMessageLen {
+		body += ": " + string(msg[:notifyDonationMessageLen-3]) + "..."
+	} else if len(msg) > 0 {
+		body += ": " + d.Message
+	}
+
+	if err := a.notifier.Notify(title, body); err != nil {
+		a.logger.Warn("failed to show desktop notification", "tip_id", d.TipID, "error", err)
+	}
+}
+
+// providerAllowed reports whether tips from provider may be printed under the
+// configured allow- and blocklists. Matching is case-insensitive.
+func (a *Astro) providerAllowed(provider string) bool {
+	match := func(list []string) bool {
+		return slices.ContainsFunc(list, func(p string) bool {
+			return strings.EqualFold(strings.TrimSpace(p), provider)
+		})
+	}
+
+	if len(a.cfg.ProviderAllowlist) > 0 && !match(a.cfg.ProviderAllowlist) {
+		return false
+	}
+	return !match(a.cfg.ProviderBlocklist)
+}
+
+// currencyAllowed reports whether tips in currency may be printed under
+// CURRENCY_ALLOWLIST.
+func (a *Astro) currencyAllowed(currency string) bool {
+	if len(a.cfg.CurrencyAllowlist) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(a.cfg.CurrencyAllowlist, func(c string) bool {
+		return currencyCode(c) == currency
+	})
+}
+
+// defaultCurrency is the currency of tips sent without one.
+func (a *Astro) defaultCurrency() string {
+	if a.cfg.DefaultCurrency != "" {
+		return currencyCode(a.cfg.DefaultCurrency)
+	}
+	return currencyCode(a.cfg.BaseCurrency)
+}
+
+// belowMinimum reports whether d is worth less than the configured minimum
+// print amount. Tips in a currency without a known rate are never skipped.
+func (a *Astro) belowMinimum(d *Donation) bool {
+	if a.cfg.MinPrintAmount <= 0 {
+		return false
+	}
+
+	amount, ok := a.baseAmount(d)
+	if !ok {
+		a.logger.Warn("no rate configured, printing tip regardless of minimum", "tip_id", d.TipID, "currency", d.Currency)
+		return false
+	}
+
+	return amount < a.cfg.MinPrintAmount
+}
+
+// baseAmount returns d's amount in the base currency. It reports false if no
+// rate is known for d's currency.
+func (a *Astro) baseAmount(d *Donation) (float64, bool) {
+	if d.ConvertedCurrency != "" {
+		return d.ConvertedAmount, true
+	}
+	amount, err := a.converter.Convert(d.Amount, d.Currency, a.cfg.BaseCurrency)
+	return amount, err == nil
+}
+
+// matchedAmount returns d's amount after applying the configured donation
+// match. Whether the match applies depends on when d was sent, not when it
+// prints, so tips queued, held for approval or retried keep their match. The
+// original amount is returned unchanged when no match applies.
+func (a *Astro) matchedAmount(d *Donation) (float64, bool) {
+	if !a.cfg.MatchActive(d.Timestamp) {
+		return d.Amount, false
+	}
+	return d.Amount * a.cfg.MatchMultiplier, true
+}
+
+// UnsubscribeTips unsubscribes every channel from tips, see Unsubscribe.
 func (a *Astro) UnsubscribeTips() error {
+	return a.Unsubscribe(TipsTopic)
+}
+
+// UnsubscribeModeration unsubscribes every channel from moderation
+// decisions, see Unsubscribe.
+func (a *Astro) UnsubscribeModeration() error {
+	return a.Unsubscribe(TipsModerationTopic)
+}
+
+// Unsubscribe unsubscribes every channel from topic, waiting up to
+// SubscribeTimeout for Astro to acknowledge each request. The subscriptions
+// are forgotten, so they aren't restored on reconnect, even if a request
+// fails. Errors for every channel are joined.
+func (a *Astro) Unsubscribe(topic string) error {
+	a.mu.Lock()
+	var subs []subscription
+	for _, sub := range a.subs {
+		if sub.topic == topic {
+			subs = append(subs, sub)
+		}
+	}
+	a.mu.Unlock()
+
+	if len(subs) == 0 {
+		return fmt.Errorf("not subscribed to %s", topic)
+	}
+	return a.unsubscribeAll(context.Background(), subs)
+}
+
+// UnsubscribeAll unsubscribes from every active topic like Unsubscribe.
+func (a *Astro) UnsubscribeAll() error {
+	a.mu.Lock()
+	subs := slices.Clone(a.subs)
+	a.mu.Unlock()
+
+	return a.unsubscribeAll(context.Background(), subs)
+}
+
+func (a *Astro) unsubscribeAll(ctx context.Context, subs []subscription) error {
+	var errs []error
+	for _, sub := range subs {
+		errs = append(errs, a.unsubscribe(ctx, sub))
+	}
+	return errors.Join(errs...)
+}
+
+// unsubscribe sends an unsubscribe message for sub and waits, up to
+// SubscribeTimeout or ctx's deadline, for Astro's response.
+func (a *Astro) unsubscribe(ctx context.Context, sub subscription) error {
+	topic := sub.topic
+	nonce, err := a.newRequest("unsubscribe", topic)
+	if err != nil {
+		return fmt.Errorf("unsubscribe %s from %s: %w", sub.channel.name, topic, err)
+	}
 	unsubscribeMessage := map[string]any{
 		"type":  "unsubscribe",
-		"nonce": uuid.New().String(),
+		"nonce": nonce,
 		"data": map[string]any{
-			"topic":      TipsTopic,
-			"token":      a.cfg.SeJWTToken,
+			"topic":      topic,
+			"token":      a.topicToken(topic, sub.channel),
 			"token_type": "jwt",
 		},
 	}
 
+	a.logger.Debug("unsubscription message", "message", a.redact(unsubscribeMessage))
+
+	a.mu.Lock()
+	a.subs = slices.DeleteFunc(a.subs, func(s subscription) bool { return s == sub })
+	a.mu.Unlock()
+
+	wait := a.awaitResponse(nonce)
 	if err := a.conn.WriteJSON(unsubscribeMessage); err != nil {
-		log.Println("Error unsubscribing:", err)
+		a.dropWaiter(nonce)
+		a.logger.Error("failed to unsubscribe", "topic", topic, "channel", sub.channel.name, "error", err)
+		return fmt.Errorf("unsubscribe %s from %s: %w", sub.channel.name, topic, classify(ErrConnection, err))
 	}
 
-	log.Println("Unsubscribed from Astro topic:", TipsTopic)
+	ctx, cancel := context.WithTimeout(ctx, a.cfg.SubscribeTimeout)
+	defer cancel()
+	if _, err := wait(ctx); err != nil {
+		a.logger.Warn("unsubscribe not acknowledged", "topic", topic, "channel", sub.channel.name, "error", err)
+		return fmt.Errorf("unsubscribe %s from %s: %w", sub.channel.name, topic, err)
+	}
 
+	a.logger.Info("unsubscribed", "topic", topic, "channel", sub.channel.name)
 	return nil
 }
 
+// TipStore returns the tip database, or nil if TIP_DB_PATH isn't set or it
+// couldn't be opened.
+func (a *Astro) TipStore() *TipStore {
+	return a.tipStore
+}
+
+// Close closes the tip log and database, committing tips still queued for
+// the database, and writes seen tips still waiting to be saved. It doesn't
+// disconnect from Astro.
+func (a *Astro) Close() error {
+	a.writeSeen()
+
+	var errs []error
+	if a.tipLog != nil {
+		errs = append(errs, a.tipLog.Close())
+	}
+	if a.tipStore != nil {
+		errs = append(errs, a.tipStore.Close())
+	}
+	return errors.Join(errs...)
+}
+
 func (a *Astro) Disconnect() error {
-	log.Println("Disconnecting from Astro")
-	return a.conn.Close()
+	a.logger.Info("disconnecting from Astro")
+
+	a.mu.Lock()
+	a.connected = false
+	a.mu.Unlock()
+	metrics.ConnectionUp.Set(0)
+	a.setState(StateDisconnected, "")
+
+	return a.closeConn()
+}
+
+// closeConn closes the current connection and stops its reader.
+func (a *Astro) closeConn() error {
+	a.mu.Lock()
+	conn, reader := a.conn, a.reader
+	a.mu.Unlock()
+
+	if reader != nil {
+		reader.close()
+	}
+	if conn == nil {
+		return nil
+	}
+	return conn.Close()
+}
+
+// ListenWithReconnect runs Listen and, whenever the connection drops,
+// reconnects with exponential backoff and restores the active subscriptions.
+// It returns when ctx is cancelled, after MaxReconnectAttempts failed
+// reconnects (ErrReconnectLimit), or once Astro rejects the token and there
+// is no SE_JWT_TOKEN_FILE to replace it from (ErrAuth).
+func (a *Astro) ListenWithReconnect(ctx context.Context) error {
+	b := newBackoff(time.Second, 30*time.Second)
+
+	for {
+		started := time.Now()
+		err := a.Listen(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		a.mu.Lock()
+		received := a.lastMessageAt.After(started)
+		a.mu.Unlock()
+		if received || isNormalClose(err) {
+			b.Reset()
+		}
+
+		if isNormalClose(err) {
+			a.logger.Info("reconnecting after Astro closed the connection")
+		} else {
+			a.logger.Warn("connection to Astro lost", "error", err)
+		}
+
+		for attempt := 1; ; attempt++ {
+			if max := a.cfg.MaxReconnectAttempts; max > 0 && attempt > max {
+				err = fmt.Errorf("%w after %d attempts: %w", ErrReconnectLimit, max, err)
+				a.setState(StateDisconnected, err.Error())
+				return err
+			}
+
+			delay := b.Next()
+			a.logger.Info("reconnecting", "attempt", attempt, "max_attempts", a.cfg.MaxReconnectAttempts, "delay", delay)
+			reason := ""
+			if err != nil {
+				reason = err.Error()
+			}
+			a.setState(StateReconnecting, reason)
+			select {
+			case <-ctx.Done():
+				a.setState(StateDisconnected, "")
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+
+			if err = a.reconnect(); err != nil {
+				a.logger.Warn("reconnect failed", "attempt", attempt, "max_attempts", a.cfg.MaxReconnectAttempts, "error", err)
+				// A rejected token stays rejected, unless it can be replaced
+				// through SE_JWT_TOKEN_FILE.
+				if errors.Is(err, ErrAuth) && a.cfg.SeJWTTokenFile == "" {
+					a.setState(StateDisconnected, err.Error())
+					return err
+				}
+				continue
+			}
+			break
+		}
+	}
+}
+
+// RunHeartbeat logs every HeartbeatInterval whether Astro is connected and
+// how long ago the last frame arrived, until ctx is cancelled. Once that is
+// longer than HealthMaxSilence it logs a warning, as the feed may be stale
+// even though the connection looks open. It returns immediately if no
+// interval is set.
+func (a *Astro) RunHeartbeat(ctx context.Context) {
+	if a.cfg.HeartbeatInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(a.cfg.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		status := a.Status()
+		age := time.Since(status.LastMessageAt).Round(time.Second)
+		switch {
+		case !status.Connected:
+			a.logger.Warn("heartbeat: not connected", "last_message_age", age)
+		case age > a.cfg.HealthMaxSilence:
+			a.logger.Warn("heartbeat: connected but no message received recently, the feed may be stale", "last_message_age", age)
+		default:
+			a.logger.Info("heartbeat: connected", "last_message_age", age)
+		}
+	}
+}
+
+// ConnectWithRetry calls Connect until it succeeds, waiting between attempts
+// with the same backoff as reconnects, e.g. while the network is still coming
+// up at boot. It gives up after maxAttempts attempts, or never if maxAttempts
+// is 0, and returns early if ctx is cancelled.
+func (a *Astro) ConnectWithRetry(ctx context.Context, maxAttempts int) error {
+	b := newBackoff(time.Second, 30*time.Second)
+
+	for attempt := 1; ; attempt++ {
+		err := a.Connect()
+		if err == nil {
+			return nil
+		}
+		if maxAttempts > 0 && attempt >= maxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		delay := b.Next()
+		a.logger.Warn("failed to connect to Astro, retrying", "attempt", attempt, "max_attempts", maxAttempts, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// authFailureLimit is how many auth errors in a row, with no new token, make
+// reconnect report the token as likely expired.
+const authFailureLimit = 3
+
+// ErrTokenLikelyExpired is returned by reconnects while Astro keeps rejecting
+// a token that hasn't changed since.
+var ErrTokenLikelyExpired = classify(ErrAuth, errors.New("token likely expired: Astro keeps rejecting it, update SE_JWT_TOKEN_FILE"))
+
+// reloadToken re-reads the JWT before a reconnect. It returns
+// ErrTokenLikelyExpired if the token is unchanged and was rejected repeatedly.
+func (a *Astro) reloadToken() error {
+	token, err := a.cfg.LoadToken()
+	if err != nil {
+		a.logger.Warn("failed to reload token, keeping the current one", "error", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err == nil && token != "" && token != a.token {
+		a.logger.Info("reloaded a new token", "token_preview", maskToken(token))
+		a.token = token
+		a.authFailures = 0
+		return nil
+	}
+	if a.authFailures >= authFailureLimit {
+		a.logger.Error("token likely expired", "auth_failures", a.authFailures)
+		return ErrTokenLikelyExpired
+	}
+	return nil
+}
+
+// isTimeout reports whether err is a network timeout or an expired deadline.
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &ne) && ne.Timeout())
+}
+
+// isNormalClose reports whether err is an intentional close by the server,
+// e.g. during maintenance.
+func isNormalClose(err error) bool {
+	var closeErr *websocket.CloseError
+	return errors.As(err, &closeErr) &&
+		(closeErr.Code == websocket.CloseNormalClosure || closeErr.Code == websocket.CloseGoingAway)
+}
+
+// reconnect replaces the current connection and re-subscribes to every topic
+// that was active before the drop.
+func (a *Astro) reconnect() error {
+	metrics.Reconnects.Inc()
+
+	if err := a.reloadToken(); err != nil {
+		return err
+	}
+
+	a.closeConn()
+	if err := a.Connect(); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	subs := slices.Clone(a.subs)
+	a.mu.Unlock()
+
+	// One channel failing to re-subscribe, e.g. with a revoked token, doesn't
+	// stop the others; the reconnect only fails if none succeeded.
+	var errs []error
+	for _, sub := range subs {
+		if err := a.subscribe(context.Background(), sub.topic, sub.channel); err != nil {
+			if len(subs) > 1 {
+				a.logger.Error("failed to re-subscribe", "topic", sub.topic, "channel", sub.channel.name, "error", err)
+			}
+			errs = append(errs, fmt.Errorf("error re-subscribing to %s: %w", sub.topic, err))
+		}
+	}
+	if len(errs) > 0 && len(errs) == len(subs) {
+		return errors.Join(errs...)
+	}
+
+	a.announceReady()
+	return nil
 }
@@ -0,0 +1,105 @@
+package streamelements
+
+import (
+	"encoding/base64"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DaniruKun/tipfax/internal/config"
+	"github.com/DaniruKun/tipfax/internal/fax"
+)
+
+// newTestAstro returns an Astro with the default config, changed by
+// configure if it isn't nil, that prints to nowhere and logs nothing.
+func newTestAstro(t *testing.T, configure func(*config.Config)) *Astro {
+	t.Helper()
+	return newTestAstroPrinter(t, fax.NewConsolePrinter(io.Discard), configure)
+}
+
+// newTestAstroPrinter is newTestAstro printing on p.
+func newTestAstroPrinter(t *testing.T, p fax.Printer, configure func(*config.Config)) *Astro {
+	t.Helper()
+
+	cfg, err := config.Defaults()
+	if err != nil {
+		t.Fatalf("config.Defaults: %v", err)
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewAstro(cfg, p, logger)
+}
+
+// testDonation returns a completed 5 USD tip from Alice with the given ID.
+func testDonation(id string) *Donation {
+	return &Donation{
+		TipID:     id,
+		Username:  "Alice",
+		Amount:    5,
+		Currency:  "USD",
+		Status:    TipStatusCompleted,
+		Provider:  "paypal",
+		Timestamp: time.Now(),
+	}
+}
+
+// testJWT is an unsigned token that passes the checks tipfax makes before
+// subscribing. The mock server accepts any token.
+func testJWT() string {
+	enc := base64.RawURLEncoding.EncodeToString
+	return enc([]byte(`{"alg":"HS256"}`)) + "." + enc([]byte(`{"exp":9999999999,"channel":"c"}`)) + ".sig"
+}
+
+// recordingPrinter is a printer that remembers what it was asked to do, one
+// entry per call: the text written, "LF", "CUT", "EMPHASIZE ON" or
+// "EMPHASIZE OFF".
+type recordingPrinter struct {
+	mu  sync.Mutex
+	ops []string
+}
+
+func (p *recordingPrinter) record(op string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ops = append(p.ops, op)
+}
+
+func (p *recordingPrinter) Write(data string) (int, error) {
+	p.record(data)
+	return len(data), nil
+}
+
+func (p *recordingPrinter) LineFeed() (int, error) {
+	p.record("LF")
+	return 1, nil
+}
+
+func (p *recordingPrinter) PrintAndCut() error {
+	p.record("CUT")
+	return nil
+}
+
+func (p *recordingPrinter) Emphasize(on bool) {
+	if on {
+		p.record("EMPHASIZE ON")
+	} else {
+		p.record("EMPHASIZE OFF")
+	}
+}
+
+// Printed reports whether a receipt was cut.
+func (p *recordingPrinter) Printed() bool {
+	return slices.Contains(p.Ops(), "CUT")
+}
+
+// Ops returns the calls recorded so far.
+func (p *recordingPrinter) Ops() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.ops)
+}
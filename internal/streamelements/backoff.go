@@ -0,0 +1,34 @@
+package streamelements
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// backoff produces exponentially growing, jittered delays between min and max.
+type backoff struct {
+	min, max time.Duration
+	cur      time.Duration
+}
+
+func newBackoff(min, max time.Duration) *backoff {
+	return &backoff{min: min, max: max}
+}
+
+// Next returns the delay to wait before the next attempt.
+func (b *backoff) Next() time.Duration {
+	if b.cur == 0 {
+		b.cur = b.min
+	} else {
+		b.cur = min(b.cur*2, b.max)
+	}
+
+	// Add up to 20% jitter so clients don't reconnect in lockstep.
+	jitter := time.Duration(rand.Int64N(int64(b.cur)/5 + 1))
+	return min(b.cur+jitter, b.max)
+}
+
+// Reset starts the sequence over from min.
+func (b *backoff) Reset() {
+	b.cur = 0
+}
@@ -0,0 +1,57 @@
+package streamelements
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/DaniruKun/tipfax/internal/fax"
+)
+
+// calibrationQRURL is encoded in the sample QR code of PrintCalibration.
+const calibrationQRURL = "https://github.com/DaniruKun/tipfax"
+
+// PrintCalibration prints a calibration receipt on the default printer: a
+// column ruler PRINTER_COLUMNS wide, text at normal and at emphasized double
+// size, and a sample QR code. Each ruler should exactly fill one line; if it
+// wraps or stops short, PRINTER_COLUMNS doesn't match the paper.
+func (a *Astro) PrintCalibration() error {
+	st, ok := a.stations[a.cfg.DefaultPrinter]
+	if !ok {
+		return classify(ErrPrinter, errors.New("no printer available"))
+	}
+
+	cols := max(a.cfg.PrinterColumns, 1)
+	lines := []string{
+		fmt.Sprintf("Calibration: PRINTER_COLUMNS=%d", cols),
+		"",
+		"Normal size:",
+	}
+	lines = append(lines, ruler(cols)...)
+	lines = append(lines, strings.Repeat("#", cols), "The quick brown fox jumps over the lazy dog.", "", "Emphasized size:")
+	emphasized := []int{len(lines), len(lines) + 1, len(lines) + 2}
+	lines = append(lines, ruler(cols/2)...)
+	lines = append(lines, "Large tip!", "", fmt.Sprintf("QR code, QR_CODE_SIZE=%d:", a.cfg.QRCodeSize))
+
+	job := a.newReceiptJob(lines)
+	job.Emphasized = emphasized
+	job.QRCodeURL = calibrationQRURL
+	return classify(ErrPrinter, st.do(func(p fax.Printer) error {
+		return a.renderer().render(p, job)
+	}))
+}
+
+// ruler returns two lines numbering cols columns: the tens digit at every
+// tenth column, dots elsewhere, then the ones digit of every column.
+func ruler(cols int) []string {
+	var tens, ones strings.Builder
+	for i := 1; i <= cols; i++ {
+		if i%10 == 0 {
+			tens.WriteByte('0' + byte(i/10%10))
+		} else {
+			tens.WriteByte('.')
+		}
+		ones.WriteByte('0' + byte(i%10))
+	}
+	return []string{tens.String(), ones.String()}
+}
@@ -0,0 +1,95 @@
+package streamelements
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// channel is a StreamElements channel whose tips are received.
+type channel struct {
+	name  string
+	token string // "" for the SE_JWT_TOKEN channel, which uses a.token so it can be reloaded
+	room  string // Astro room ID, set once a subscription succeeds; guarded by a.mu
+}
+
+// subscription is an accepted subscription, restored after a reconnect.
+type subscription struct {
+	topic   string
+	channel *channel
+}
+
+// setupChannels creates the SE_JWT_TOKEN channel followed by one channel per
+// CHANNEL_TOKENS entry, sorted by name.
+func (a *Astro) setupChannels() {
+	a.channels = []*channel{{name: a.cfg.ChannelName}}
+	for _, name := range slices.Sorted(maps.Keys(a.cfg.ChannelTokens)) {
+		a.channels = append(a.channels, &channel{name: name, token: a.cfg.ChannelTokens[name]})
+	}
+}
+
+// channelToken returns the token to subscribe to ch with.
+func (a *Astro) channelToken(ch *channel) string {
+	if ch.token != "" {
+		return ch.token
+	}
+	return a.jwt()
+}
+
+// topicToken returns the token to subscribe to topic on ch with: the
+// SE_MODERATION_JWT_TOKEN for moderation on the SE_JWT_TOKEN channel if set,
+// otherwise ch's token.
+func (a *Astro) topicToken(topic string, ch *channel) string {
+	if topic == TipsModerationTopic && ch.token == "" && a.cfg.SeModerationJWTToken != "" {
+		return a.cfg.SeModerationJWTToken
+	}
+	return a.channelToken(ch)
+}
+
+// channelForRoom returns the name of the channel subscribed in room.
+// Messages from an unknown room are attributed to the SE_JWT_TOKEN channel.
+func (a *Astro) channelForRoom(room string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if room != "" {
+		for _, ch := range a.channels {
+			if ch.room == room {
+				return ch.name
+			}
+		}
+	}
+	return a.channels[0].name
+}
+
+// subscribeChannels subscribes to topic for every channel. A channel that
+// fails to subscribe is logged and the others still are; an error is only
+// returned if none succeeded.
+func (a *Astro) subscribeChannels(ctx context.Context, topic string) error {
+	if len(a.channels) == 1 {
+		return a.subscribeChannel(ctx, topic, a.channels[0])
+	}
+
+	var errs []error
+	for _, ch := range a.channels {
+		if err := a.subscribeChannel(ctx, topic, ch); err != nil {
+			a.logger.Error("failed to subscribe channel", "channel", ch.name, "topic", topic, "error", err)
+			errs = append(errs, fmt.Errorf("channel %s: %w", ch.name, err))
+		}
+	}
+	if len(errs) == len(a.channels) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+func (a *Astro) subscribeChannel(ctx context.Context, topic string, ch *channel) error {
+	if ch.token == "" {
+		if err := a.checkToken(); err != nil {
+			return err
+		}
+	}
+	return a.subscribe(ctx, topic, ch)
+}
@@ -0,0 +1,45 @@
+package streamelements
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CompactReceipts reports whether tips are printed as compact receipts, see
+// SetCompactReceipts.
+func (a *Astro) CompactReceipts() bool {
+	return a.compact.Load()
+}
+
+// SetCompactReceipts switches between compact receipts, one line per tip
+// where it fits with no header, footer or QR code, and the full receipt
+// layout. It starts out as COMPACT_RECEIPT.
+func (a *Astro) SetCompactReceipts(on bool) {
+	if a.compact.Swap(on) != on {
+		a.logger.Info("switched receipt layout", "compact", on)
+	}
+}
+
+// buildCompactReceipt prints d as "$5.00 Alice: thanks!", wrapped to a
+// second line if needed, and feeds only COMPACT_FEED_LINES before the cut.
+func (a *Astro) buildCompactReceipt(d *Donation) ReceiptJob {
+	data := a.newReceiptData(d)
+
+	var b strings.Builder
+	if a.cfg.PrintChannel && data.Channel != "" {
+		fmt.Fprintf(&b, "[%s] ", data.Channel)
+	}
+	b.WriteString(data.FormattedAmount)
+	if data.Collapsed > 0 {
+		fmt.Fprintf(&b, " (x%d)", data.Collapsed)
+	}
+	b.WriteString(" " + data.Username)
+	if data.Message != "" {
+		b.WriteString(": " + data.Message)
+	}
+
+	job := a.newReceiptJob([]string{b.String()})
+	job.Separator = false
+	job.FeedLines = a.cfg.CompactFeedLines
+	return job
+}
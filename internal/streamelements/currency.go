@@ -0,0 +1,147 @@
+package streamelements
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/DaniruKun/tipfax/internal/config"
+)
+
+// CurrencyConverter converts amounts using a static rate table. Rates give the
+// value of one unit of a currency in the base currency.
+type CurrencyConverter struct {
+	base  string
+	rates map[string]float64
+}
+
+// NewCurrencyConverter creates a converter for rates relative to base.
+func NewCurrencyConverter(base string, rates map[string]float64) *CurrencyConverter {
+	c := &CurrencyConverter{
+		base:  currencyCode(base),
+		rates: make(map[string]float64, len(rates)),
+	}
+	for currency, rate := range rates {
+		c.rates[currencyCode(currency)] = rate
+	}
+	return c
+}
+
+// Convert converts amount from one currency to another. It returns an error
+// if either currency has no known rate.
+func (c *CurrencyConverter) Convert(amount float64, from, to string) (float64, error) {
+	from, to = currencyCode(from), currencyCode(to)
+	if from == to {
+		return amount, nil
+	}
+
+	fromRate, err := c.rate(from)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := c.rate(to)
+	if err != nil {
+		return 0, err
+	}
+	return amount * fromRate / toRate, nil
+}
+
+func (c *CurrencyConverter) rate(currency string) (float64, error) {
+	if currency == c.base {
+		return 1, nil
+	}
+	rate, ok := c.rates[currency]
+	if !ok || rate <= 0 {
+		return 0, fmt.Errorf("no rate configured for %s", currency)
+	}
+	return rate, nil
+}
+
+// normalizeAmountUnit converts d.Amount to major units, e.g. dollars, if its
+// provider reports amounts in minor units, e.g. cents, per
+// AMOUNT_IN_MINOR_UNITS. Providers missing from a configured
+// AMOUNT_IN_MINOR_UNITS are warned about once and assumed to use major units.
+func (a *Astro) normalizeAmountUnit(d *Donation) {
+	units := a.cfg.AmountInMinorUnits
+	if len(units) == 0 {
+		return
+	}
+
+	provider := strings.ToLower(d.Provider)
+	minor, ok := false, false
+	for name, m := range units {
+		if strings.EqualFold(name, provider) {
+			minor, ok = m, true
+			break
+		}
+	}
+	if !ok {
+		a.mu.Lock()
+		warned := a.unitWarned[provider]
+		if a.unitWarned == nil {
+			a.unitWarned = make(map[string]bool)
+		}
+		a.unitWarned[provider] = true
+		a.mu.Unlock()
+		if !warned {
+			a.logger.Warn("provider missing from AMOUNT_IN_MINOR_UNITS, assuming amounts in major units", "provider", d.Provider)
+		}
+		return
+	}
+	if minor {
+		d.Amount /= minorUnits(currencyCode(d.Currency))
+	}
+}
+
+// convertDonation fills in d's amount in the base currency. If d's currency
+// has no known rate the error is logged and d is left unconverted.
+func (a *Astro) convertDonation(d *Donation) {
+	if d.ConvertedCurrency != "" {
+		return
+	}
+
+	amount, err := a.converter.Convert(d.Amount, d.Currency, a.cfg.BaseCurrency)
+	if err != nil {
+		a.logger.Warn("can't convert tip to base currency", "tip_id", d.TipID, "currency", d.Currency, "error", err)
+		return
+	}
+	d.ConvertedAmount = amount
+	d.ConvertedCurrency = currencyCode(a.cfg.BaseCurrency)
+}
+
+// formatAmount renders amount for display using the configured format for
+// currency, or as "12.34 CODE" with the receipt language's separators if
+// there is none.
+func (a *Astro) formatAmount(amount float64, currency string) string {
+	currency = currencyCode(currency)
+	cf, ok := a.cfg.CurrencyFormats[currency]
+	if !ok {
+		cf = config.CurrencyFormat{
+			Pattern:      "%s " + currency,
+			DecimalSep:   a.locale.decimalSep,
+			ThousandsSep: a.locale.thousandsSep,
+			Decimals:     2,
+		}
+	}
+
+	num := strconv.FormatFloat(math.Abs(amount), 'f', cf.Decimals, 64)
+	intPart, frac, _ := strings.Cut(num, ".")
+	if cf.ThousandsSep != "" {
+		var b strings.Builder
+		for i, r := range intPart {
+			if i > 0 && (len(intPart)-i)%3 == 0 {
+				b.WriteString(cf.ThousandsSep)
+			}
+			b.WriteRune(r)
+		}
+		intPart = b.String()
+	}
+	if frac != "" {
+		intPart += cf.DecimalSep + frac
+	}
+	if amount < 0 {
+		intPart = "-" + intPart
+	}
+	return strings.Replace(cf.Pattern, "%s", intPart, 1)
+}
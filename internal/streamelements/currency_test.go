@@ -0,0 +1,86 @@
+package streamelements
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/DaniruKun/tipfax/internal/config"
+	"github.com/DaniruKun/tipfax/internal/fax"
+)
+
+func TestNormalizeAmountUnit(t *testing.T) {
+	units := map[string]bool{"stripe": true, "PayPal": false}
+
+	tests := []struct {
+		name     string
+		units    map[string]bool
+		provider string
+		amount   float64
+		currency string
+		want     float64
+	}{
+		{"cents", units, "stripe", 500, "USD", 5},
+		{"provider case", units, "Stripe", 1234, "EUR", 12.34},
+		{"zero-decimal currency", units, "stripe", 500, "JPY", 500},
+		{"three-decimal currency", units, "stripe", 5000, "KWD", 5},
+		{"currency alias", units, "stripe", 250, "$", 2.5},
+		{"major units", units, "paypal", 5, "USD", 5},
+		{"unconfigured provider", units, "kofi", 5, "USD", 5},
+		{"not configured", nil, "stripe", 500, "USD", 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAstro(t, func(cfg *config.Config) { cfg.AmountInMinorUnits = tt.units })
+
+			d := testDonation("t1")
+			d.Provider, d.Amount, d.Currency = tt.provider, tt.amount, tt.currency
+			a.normalizeAmountUnit(d)
+			if d.Amount != tt.want {
+				t.Errorf("amount = %v, want %v", d.Amount, tt.want)
+			}
+		})
+	}
+}
+
+// TestCentsReceipt prints a tip from a provider reporting cents and checks
+// it isn't printed at 100 times its value.
+func TestCentsReceipt(t *testing.T) {
+	p := &recordingPrinter{}
+	a := newTestAstroPrinter(t, p, func(cfg *config.Config) {
+		cfg.AmountInMinorUnits = map[string]bool{"stripe": true}
+	})
+
+	data := json.RawMessage(`{"_id":"t1","provider":"stripe","status":"success",` +
+		`"donation":{"user":{"username":"Alice"},"amount":500,"currency":"USD"}}`)
+	if err := a.handleTipMessage(Message{Type: "message", Topic: TipsTopic, Data: data}); err != nil {
+		t.Fatalf("handleTipMessage: %v", err)
+	}
+
+	receipt := strings.Join(p.Ops(), "")
+	if !strings.Contains(receipt, "5.00 USD") || strings.Contains(receipt, "500.00") {
+		t.Errorf("receipt %q doesn't show 5.00 USD", receipt)
+	}
+}
+
+func TestUnconfiguredProviderWarnsOnce(t *testing.T) {
+	cfg, err := config.Defaults()
+	if err != nil {
+		t.Fatalf("config.Defaults: %v", err)
+	}
+	cfg.AmountInMinorUnits = map[string]bool{"stripe": true}
+	var logs logBuffer
+	a := NewAstro(cfg, fax.NewConsolePrinter(io.Discard), slog.New(slog.NewTextHandler(&logs, nil)))
+
+	for range 3 {
+		d := testDonation("t1")
+		d.Provider = "kofi"
+		a.normalizeAmountUnit(d)
+	}
+
+	if n := strings.Count(logs.String(), "provider missing from AMOUNT_IN_MINOR_UNITS"); n != 1 {
+		t.Errorf("warned %d times, want once", n)
+	}
+}
@@ -0,0 +1,113 @@
+package streamelements
+
+import "strings"
+
+// isoCurrencies are the active ISO 4217 currency codes.
+var isoCurrencies = makeSet(strings.Fields(`
+	AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB
+	BRL BSD BTN BWP BYN BZD CAD CDF CHF CLP CNY COP CRC CUP CVE CZK DJF DKK DOP
+	DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GNF GTQ GYD HKD HNL HTG HUF
+	IDR ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF KPW KRW KWD KYD KZT LAK
+	LBP LKR LRD LSL LYD MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MYR MZN
+	NAD NGN NIO NOK NPR NZD OMR PAB PEN PGK PHP PKR PLN PYG QAR RON RSD RUB RWF
+	SAR SBD SCR SDG SEK SGD SHP SLE SOS SRD SSP STN SVC SYP SZL THB TJS TMT TND
+	TOP TRY TTD TWD TZS UAH UGX USD UYU UZS VES VND VUV WST XAF XCD XCG XOF XPF
+	YER ZAR ZMW ZWG
+`))
+
+// Currencies whose minor unit isn't 1/100 of the major unit. Every other
+// currency has 2 decimals.
+var (
+	zeroDecimalCurrencies  = makeSet(strings.Fields("BIF CLP DJF GNF ISK JPY KMF KRW PYG RWF UGX VND VUV XAF XOF XPF"))
+	threeDecimalCurrencies = makeSet(strings.Fields("BHD IQD JOD KWD LYD OMR TND"))
+)
+
+// minorUnits returns how many minor units, e.g. cents, make one unit of the
+// ISO 4217 currency code.
+func minorUnits(code string) float64 {
+	switch {
+	case zeroDecimalCurrencies[code]:
+		return 1
+	case threeDecimalCurrencies[code]:
+		return 1000
+	}
+	return 100
+}
+
+// currencyAliases maps symbols and names providers have been seen to send,
+// uppercased, to ISO 4217 codes.
+var currencyAliases = map[string]string{
+	"$":       "USD",
+	"US$":     "USD",
+	"DOLLAR":  "USD",
+	"DOLLARS": "USD",
+	"€":       "EUR",
+	"EURO":    "EUR",
+	"EUROS":   "EUR",
+	"£":       "GBP",
+	"POUND":   "GBP",
+	"POUNDS":  "GBP",
+	"¥":       "JPY",
+	"YEN":     "JPY",
+	"₽":       "RUB",
+	"RUBLE":   "RUB",
+	"RUBLES":  "RUB",
+	"R$":      "BRL",
+	"REAL":    "BRL",
+	"REAIS":   "BRL",
+	"₹":       "INR",
+	"RUPEE":   "INR",
+	"RUPEES":  "INR",
+	"₩":       "KRW",
+	"WON":     "KRW",
+	"ZŁ":      "PLN",
+	"ZLOTY":   "PLN",
+	"₺":       "TRY",
+	"LIRA":    "TRY",
+	"C$":      "CAD",
+	"CA$":     "CAD",
+	"A$":      "AUD",
+	"AU$":     "AUD",
+	"NZ$":     "NZD",
+	"HK$":     "HKD",
+	"MX$":     "MXN",
+	"RMB":     "CNY",
+	"YUAN":    "CNY",
+
+	// Superseded codes still used by some providers.
+	"RUR": "RUB",
+	"BYR": "BYN",
+	"VEF": "VES",
+	"MRO": "MRU",
+	"STD": "STN",
+	"ZWL": "ZWG",
+}
+
+// normalizeCurrency trims and uppercases s and maps known aliases to their
+// ISO 4217 code. It reports whether the result is a known code; if not, the
+// trimmed, uppercased s is returned so the tip can still be shown.
+func normalizeCurrency(s string) (string, bool) {
+	code := strings.ToUpper(strings.TrimSpace(s))
+	if isoCurrencies[code] {
+		return code, true
+	}
+	if alias, ok := currencyAliases[code]; ok {
+		return alias, true
+	}
+	return code, false
+}
+
+// currencyCode is normalizeCurrency for values that were already validated
+// or logged, e.g. configured currencies.
+func currencyCode(s string) string {
+	code, _ := normalizeCurrency(s)
+	return code
+}
+
+func makeSet(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
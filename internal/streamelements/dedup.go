@@ -0,0 +1,125 @@
+package streamelements
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// seenSet remembers keys for a limited time and up to a maximum count, so
+// tips re-delivered after a reconnect can be recognised.
+type seenSet struct {
+	mu       sync.Mutex
+	window   time.Duration
+	capacity int
+	seen     map[string]time.Time
+	order    []string // keys in insertion order, oldest first
+}
+
+func newSeenSet(window time.Duration, capacity int) *seenSet {
+	return &seenSet{window: window, capacity: capacity, seen: make(map[string]time.Time)}
+}
+
+// Seen reports whether key was already recorded within the window, and
+// records it if not.
+func (s *seenSet) Seen(key string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Keys are inserted in time order, so expired ones are at the front.
+	for len(s.order) > 0 {
+		oldest := s.order[0]
+		if now.Sub(s.seen[oldest]) < s.window {
+			break
+		}
+		delete(s.seen, oldest)
+		s.order = s.order[1:]
+	}
+
+	if _, ok := s.seen[key]; ok {
+		return true
+	}
+
+	s.seen[key] = now
+	s.order = append(s.order, key)
+	if s.capacity > 0 && len(s.order) > s.capacity {
+		delete(s.seen, s.order[0])
+		s.order = s.order[1:]
+	}
+
+	return false
+}
+
+// Has reports whether key was recorded within the window, without recording
+// it.
+func (s *seenSet) Has(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.seen[key]
+	return ok && time.Since(t) < s.window
+}
+
+// seenEntry is a key of a seenSet and when it was recorded.
+type seenEntry struct {
+	Key  string    `json:"key"`
+	Seen time.Time `json:"seen"`
+}
+
+// entries returns the keys recorded within the window, oldest first.
+func (s *seenSet) entries(now time.Time) []seenEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]seenEntry, 0, len(s.order))
+	for _, key := range s.order {
+		if t := s.seen[key]; now.Sub(t) < s.window {
+			entries = append(entries, seenEntry{Key: key, Seen: t})
+		}
+	}
+	return entries
+}
+
+// restore records entries, oldest first, keeping their times. Entries
+// outside the window or already present are skipped. It returns how many
+// were added.
+func (s *seenSet) restore(entries []seenEntry, now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	added := 0
+	for _, e := range entries {
+		if _, ok := s.seen[e.Key]; ok || now.Sub(e.Seen) >= s.window {
+			continue
+		}
+		s.seen[e.Key] = e.Seen
+		s.order = append(s.order, e.Key)
+		added++
+		if s.capacity > 0 && len(s.order) > s.capacity {
+			delete(s.seen, s.order[0])
+			s.order = s.order[1:]
+			added--
+		}
+	}
+	return added
+}
+
+// dedupKey identifies d for deduplication. Tips without an ID fall back to a
+// hash of their content, including the event timestamp when there is one, so
+// they aren't all treated as the same tip.
+func dedupKey(d *Donation) string {
+	if d.TipID != "" {
+		// Include the status so a later update, e.g. to refunded, isn't
+		// mistaken for a duplicate.
+		return d.TipID + ":" + string(d.Status)
+	}
+
+	var ts string
+	if d.eventTimestamp {
+		ts = d.Timestamp.UTC().Format(time.RFC3339Nano)
+	}
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s\x00%.2f\x00%s\x00%s\x00%s", d.Username, d.Amount, d.Currency, d.Message, ts))
+	return "hash:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,87 @@
+package streamelements
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+)
+
+// dedupSaveDelay is how long saveSeen waits before writing DEDUP_PATH, so a
+// burst of tips is written once.
+const dedupSaveDelay = time.Second
+
+// saveSeen schedules writing the tips seen within DEDUP_WINDOW to
+// DEDUP_PATH, if set, so tips Astro re-delivers after a restart aren't
+// printed twice. The file is rewritten in full, so this happens on a timer
+// goroutine rather than on the caller's, which is the read loop.
+func (a *Astro) saveSeen() {
+	if a.cfg.DedupPath == "" {
+		return
+	}
+
+	a.mu.Lock()
+	scheduled := a.seenSaveDue
+	a.seenSaveDue = true
+	a.mu.Unlock()
+	if !scheduled {
+		time.AfterFunc(dedupSaveDelay, a.writeSeen)
+	}
+}
+
+// writeSeen writes the seen tips to DEDUP_PATH if saveSeen scheduled it and
+// no one did so since; Close calls it too, so nothing is lost on shutdown.
+// Like savePending it writes a temporary file and renames it, and failures
+// are only logged.
+func (a *Astro) writeSeen() {
+	path := a.cfg.DedupPath
+
+	a.seenSave.Lock()
+	defer a.seenSave.Unlock()
+
+	// Cleared before taking the entries, so a tip seen from here on
+	// schedules another save rather than being lost.
+	a.mu.Lock()
+	due := a.seenSaveDue
+	a.seenSaveDue = false
+	a.mu.Unlock()
+	if !due {
+		return
+	}
+
+	data, err := json.Marshal(a.seen.entries(time.Now()))
+	if err == nil {
+		tmp := path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0o644); err == nil {
+			err = os.Rename(tmp, path)
+		}
+	}
+	if err != nil {
+		a.logger.Warn("failed to save seen tips", "path", path, "error", err)
+	}
+}
+
+// loadSeen restores the tips saved by saveSeen, dropping those older than
+// DEDUP_WINDOW.
+func (a *Astro) loadSeen() {
+	path := a.cfg.DedupPath
+	if path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	var saved []seenEntry
+	if err == nil {
+		err = json.Unmarshal(data, &saved)
+	}
+	if err != nil {
+		a.logger.Warn("failed to load seen tips, starting without them", "path", path, "error", err)
+		return
+	}
+
+	restored := a.seen.restore(saved, time.Now())
+	a.logger.Info("restored seen tips for deduplication", "path", path, "tips", restored, "expired", len(saved)-restored)
+}
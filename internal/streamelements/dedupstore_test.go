@@ -0,0 +1,96 @@
+package streamelements
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DaniruKun/tipfax/internal/config"
+)
+
+// TestSeenSurvivesRestart handles tips, closes Astro and checks a new one
+// using the same DEDUP_PATH skips them when they are re-delivered.
+func TestSeenSurvivesRestart(t *testing.T) {
+	tests := []struct {
+		name  string
+		tips  int
+		again int // tips re-delivered after the restart, from the first
+	}{
+		{"one tip", 1, 1},
+		{"burst", 50, 50},
+		{"some re-delivered", 10, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "seen.json")
+			configure := func(cfg *config.Config) { cfg.DedupPath = path }
+			tip := func(i int) Message {
+				return Message{Type: "message", Topic: TipsTopic, Data: tipJSON(fmt.Sprintf(`"t%d"`, i), "success")}
+			}
+
+			a := newTestAstro(t, configure)
+			for i := range tt.tips {
+				if err := a.handleTipMessage(tip(i)); err != nil {
+					t.Fatalf("handleTipMessage: %v", err)
+				}
+			}
+			// Saving is left to a timer, off the read loop.
+			if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("DEDUP_PATH written while handling tips: %v", err)
+			}
+			if err := a.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			b := newTestAstro(t, configure)
+			for i := range tt.again {
+				if err := b.handleTipMessage(tip(i)); err != nil {
+					t.Fatalf("handleTipMessage: %v", err)
+				}
+			}
+			if got := len(b.RecentTips(recentTipsMax)); got != 0 {
+				t.Errorf("%d re-delivered tips handled again after the restart", got)
+			}
+		})
+	}
+}
+
+// TestSeenSavedAfterDelay checks a burst of tips is saved by the timer
+// without waiting for Close.
+func TestSeenSavedAfterDelay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.json")
+	a := newTestAstro(t, func(cfg *config.Config) { cfg.DedupPath = path })
+	for i := range 5 {
+		msg := Message{Type: "message", Topic: TipsTopic, Data: tipJSON(fmt.Sprintf(`"t%d"`, i), "success")}
+		if err := a.handleTipMessage(msg); err != nil {
+			t.Fatalf("handleTipMessage: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(dedupSaveDelay + 2*time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("DEDUP_PATH not written within %s", dedupSaveDelay)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	var saved []seenEntry
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &saved)
+	}
+	if err != nil {
+		t.Fatalf("read DEDUP_PATH: %v", err)
+	}
+	if len(saved) != 5 {
+		t.Errorf("saved %d tips, want 5", len(saved))
+	}
+}
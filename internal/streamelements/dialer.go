@@ -0,0 +1,51 @@
+package streamelements
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/DaniruKun/tipfax/internal/config"
+	"github.com/gorilla/websocket"
+)
+
+// newDialer builds the WebSocket dialer for Astro from cfg. Without ProxyURL
+// it honors the HTTPS_PROXY and NO_PROXY environment variables.
+func newDialer(cfg *config.Config) (*websocket.Dialer, error) {
+	d := &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: cfg.HandshakeTimeout,
+	}
+
+	if cfg.ProxyURL != "" {
+		u, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		d.Proxy = http.ProxyURL(u)
+	}
+
+	if cfg.TLSCAFile != "" || cfg.TLSInsecureSkipVerify {
+		tlsConfig := &tls.Config{InsecureSkipVerify: cfg.TLSInsecureSkipVerify}
+		if cfg.TLSCAFile != "" {
+			pem, err := os.ReadFile(cfg.TLSCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("read CA file: %w", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", cfg.TLSCAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		d.TLSClientConfig = tlsConfig
+	}
+
+	return d, nil
+}
@@ -0,0 +1,243 @@
+package streamelements
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Donation is a parsed tip from the channel.tips topic.
+type Donation struct {
+	TipID    string    `json:"tipId"`
+	Username string    `json:"username"`
+	Amount   float64   `json:"amount"`
+	Currency string    `json:"currency"`
+	Message  string    `json:"message"`
+	Status   TipStatus `json:"status"`
+	Provider string    `json:"provider"`
+
+	// Timestamp is when the tip was created according to the event, or when
+	// it was received if the event carries no timestamp.
+	Timestamp time.Time `json:"timestamp"`
+
+	// ConvertedAmount is Amount in ConvertedCurrency, the base currency. Both
+	// are zero if no rate is known for Currency.
+	ConvertedAmount   float64 `json:"convertedAmount,omitempty"`
+	ConvertedCurrency string  `json:"convertedCurrency,omitempty"`
+
+	// Moderation is the tip's moderation state, empty for unmoderated tips.
+	Moderation ModerationAction `json:"moderation,omitempty"`
+
+	// Channel is the name of the channel the tip was sent to, see
+	// CHANNEL_NAME and CHANNEL_TOKENS.
+	Channel string `json:"channel,omitempty"`
+
+	// eventTimestamp reports whether Timestamp came from the event.
+	eventTimestamp bool
+
+	// donorTips and donorTotal are the donor's tip count and total in the
+	// base currency this session, including this tip, as of when it was
+	// received. Both are zero for anonymous tips.
+	donorTips  int
+	donorTotal float64
+
+	// receivedAt is when the tip was handled, for print latency.
+	receivedAt time.Time
+
+	// collapsed is the number of identical tips this one stands for, see
+	// spamGuard. It is 0 for an ordinary tip.
+	collapsed int
+}
+
+// tipEvent mirrors the wire format of a channel.tips message payload.
+type tipEvent struct {
+	ID        flexID      `json:"_id"`
+	Status    string      `json:"status"`
+	Provider  string      `json:"provider"`
+	Approved  string      `json:"approved"`
+	CreatedAt flexTime    `json:"createdAt"`
+	Timestamp flexTime    `json:"timestamp"`
+	Amount    *flexAmount `json:"amount"` // some providers put the amount here
+	Donation  *struct {
+		User struct {
+			Username string `json:"username"`
+		} `json:"user"`
+		Message   string      `json:"message"`
+		Amount    *flexAmount `json:"amount"`
+		TipAmount *flexAmount `json:"tipAmount"`
+		Currency  string      `json:"currency"`
+	} `json:"donation"`
+}
+
+// amount returns the first amount found in the event's known amount fields.
+func (ev *tipEvent) amount() (float64, bool) {
+	for _, a := range []*flexAmount{ev.Donation.Amount, ev.Donation.TipAmount, ev.Amount} {
+		if a != nil {
+			return float64(*a), true
+		}
+	}
+	return 0, false
+}
+
+// flexAmount accepts an amount encoded as a JSON number, as a numeric string,
+// or as an object holding either under "value".
+type flexAmount float64
+
+func (f *flexAmount) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+
+	var n float64
+	if err := json.Unmarshal(b, &n); err == nil {
+		*f = flexAmount(n)
+		return nil
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		var obj struct {
+			Value *flexAmount `json:"value"`
+		}
+		if err := json.Unmarshal(b, &obj); err != nil {
+			return err
+		}
+		if obj.Value == nil {
+			return fmt.Errorf("amount object has no value: %s", b)
+		}
+		*f = *obj.Value
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("amount is neither a number, a string nor an object: %s", b)
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return fmt.Errorf("amount %q is not numeric: %w", s, err)
+	}
+	*f = flexAmount(n)
+	return nil
+}
+
+// flexTime accepts a time encoded either as an RFC3339 string or as Unix
+// milliseconds, given as a JSON number or a numeric string.
+type flexTime time.Time
+
+func (f *flexTime) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+
+	var ms float64
+	if err := json.Unmarshal(b, &ms); err == nil {
+		*f = flexTime(time.UnixMilli(int64(ms)))
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("timestamp is neither a number nor a string: %s", b)
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		*f = flexTime(time.UnixMilli(ms))
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return fmt.Errorf("timestamp %q is neither RFC3339 nor Unix milliseconds: %w", s, err)
+	}
+	*f = flexTime(t)
+	return nil
+}
+
+// ParseDonation decodes the data payload of a channel.tips message. Currency
+// is left empty if the tip has none. Oddities that don't stop the tip from
+// being handled, such as an unrecognized currency, are logged to logger, or
+// slog.Default() if it is nil.
+func ParseDonation(data json.RawMessage, logger *slog.Logger) (*Donation, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	var ev tipEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return nil, fmt.Errorf("decode tip event: %w", err)
+	}
+	if ev.Donation == nil {
+		return nil, errors.New("tip event has no donation data")
+	}
+
+	amount, ok := ev.amount()
+	if !ok {
+		logger.Warn("tip event has no amount in any known field, recording it as 0",
+			"tip_id", ev.ID, "donation", string(rawDonation(data)))
+	}
+
+	d := &Donation{
+		TipID:    firstID(ev.ID),
+		Username: ev.Donation.User.Username,
+		Amount:   amount,
+		Currency: ev.Donation.Currency,
+		Message:  ev.Donation.Message,
+		Status:   parseTipStatus(ev.Status, logger),
+		Provider: ev.Provider,
+	}
+	if ev.Approved != "" {
+		if action, err := parseModerationAction(ev.Approved); err == nil {
+			d.Moderation = action
+		}
+	}
+	switch {
+	case !time.Time(ev.CreatedAt).IsZero():
+		d.Timestamp, d.eventTimestamp = time.Time(ev.CreatedAt), true
+	case !time.Time(ev.Timestamp).IsZero():
+		d.Timestamp, d.eventTimestamp = time.Time(ev.Timestamp), true
+	default:
+		d.Timestamp = time.Now()
+	}
+	if d.Username == "" {
+		d.Username = "Unknown"
+	}
+	switch code, ok := normalizeCurrency(d.Currency); {
+	case code == "":
+		d.Currency = ""
+	case !ok:
+		logger.Warn("unrecognized currency, using it as is", "tip_id", d.TipID, "currency", ev.Donation.Currency)
+		d.Currency = code
+	default:
+		d.Currency = code
+	}
+	if d.Provider == "" {
+		d.Provider = "unknown"
+	}
+
+	return d, nil
+}
+
+// rawDonation returns the raw donation object of a tip event, for logging.
+func rawDonation(data json.RawMessage) json.RawMessage {
+	var ev struct {
+		Donation json.RawMessage `json:"donation"`
+	}
+	json.Unmarshal(data, &ev)
+	return ev.Donation
+}
+
+// Pending reports whether the tip is still awaiting a moderation decision.
+func (d *Donation) Pending() bool {
+	switch d.Moderation {
+	case ModerationApproved, ModerationDenied:
+		return false
+	}
+	return d.Moderation == ModerationPending || d.Status == TipStatusPending
+}
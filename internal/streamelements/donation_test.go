@@ -0,0 +1,39 @@
+package streamelements
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestParseDonationLogs checks that ParseDonation reports oddities to the
+// logger it is given.
+func TestParseDonationLogs(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantWarn string // "" for no warning
+	}{
+		{"clean", `{"_id":"t1","status":"success","donation":{"amount":5,"currency":"USD"}}`, ""},
+		{"no amount", `{"_id":"t1","status":"success","donation":{"currency":"USD"}}`, "tip event has no amount"},
+		{"unknown currency", `{"_id":"t1","status":"success","donation":{"amount":5,"currency":"DOGE"}}`, "unrecognized currency"},
+		{"unknown status", `{"_id":"t1","status":"settled","donation":{"amount":5,"currency":"USD"}}`, "unknown tip status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs logBuffer
+			if _, err := ParseDonation(json.RawMessage(tt.data), slog.New(slog.NewTextHandler(&logs, nil))); err != nil {
+				t.Fatalf("ParseDonation: %v", err)
+			}
+
+			out := logs.String()
+			switch {
+			case tt.wantWarn == "" && out != "":
+				t.Errorf("unexpected log output: %s", out)
+			case tt.wantWarn != "" && !strings.Contains(out, tt.wantWarn):
+				t.Errorf("log output %q doesn't contain %q", out, tt.wantWarn)
+			}
+		})
+	}
+}
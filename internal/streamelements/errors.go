@@ -0,0 +1,43 @@
+package streamelements
+
+import "errors"
+
+// Error classes, so callers and monitoring can tell failures apart with
+// errors.Is. Errors returned by Astro's methods wrap at most one of them, and
+// the more specific errors such as ErrConnectTimeout belong to one too.
+var (
+	// ErrAuth means Astro rejected the token, or there is no usable token.
+	// Retrying with the same token won't help.
+	ErrAuth = errors.New("authentication failed")
+
+	// ErrConnection means the connection to Astro couldn't be opened or was
+	// lost. Reconnecting may fix it.
+	ErrConnection = errors.New("connection to Astro failed")
+
+	// ErrSubscription means Astro rejected a subscription for a reason other
+	// than the token, or didn't answer it.
+	ErrSubscription = errors.New("subscription failed")
+
+	// ErrPrinter means a receipt couldn't be printed.
+	ErrPrinter = errors.New("printer failed")
+
+	// ErrInvalidMessage means a notification from Astro couldn't be decoded.
+	ErrInvalidMessage = errors.New("invalid message")
+)
+
+// classify marks err as belonging to class without changing its message. It
+// returns nil if err is nil.
+func classify(class, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classError{err: err, class: class}
+}
+
+type classError struct {
+	err   error
+	class error
+}
+
+func (e *classError) Error() string   { return e.err.Error() }
+func (e *classError) Unwrap() []error { return []error{e.err, e.class} }
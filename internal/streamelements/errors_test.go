@@ -0,0 +1,111 @@
+package streamelements
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DaniruKun/tipfax/internal/config"
+)
+
+var errorClasses = []error{ErrAuth, ErrConnection, ErrSubscription, ErrPrinter, ErrInvalidMessage}
+
+// checkClass fails t unless err belongs to want and no other class.
+func checkClass(t *testing.T, err, want error) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("got no error, want %v", want)
+	}
+	for _, class := range errorClasses {
+		if got := errors.Is(err, class); got != (class == want) {
+			t.Errorf("errors.Is(%q, %v) = %v, want %v", err, class, got, class == want)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	if err := classify(ErrAuth, nil); err != nil {
+		t.Fatalf("classify(ErrAuth, nil) = %v, want nil", err)
+	}
+
+	inner := errors.New("boom")
+	for _, class := range errorClasses {
+		t.Run(class.Error(), func(t *testing.T) {
+			err := classify(class, inner)
+			checkClass(t, err, class)
+			if !errors.Is(err, inner) {
+				t.Error("classified error doesn't wrap the original")
+			}
+			if err.Error() != "boom" {
+				t.Errorf("message changed to %q", err)
+			}
+		})
+	}
+}
+
+// TestErrorClasses checks the class of the errors Astro's methods return for
+// each kind of failure.
+func TestErrorClasses(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedURL := "ws" + strings.TrimPrefix(closed.URL, "http")
+	closed.Close()
+
+	tests := []struct {
+		name string
+		run  func(a *Astro) error
+		want error
+	}{
+		{"empty token", func(a *Astro) error {
+			a.token = ""
+			return a.checkToken()
+		}, ErrAuth},
+		{"malformed token", func(a *Astro) error {
+			a.token = "not-a-jwt"
+			return a.checkToken()
+		}, ErrAuth},
+		{"connection refused", func(a *Astro) error {
+			a.cfg.AstroURL = closedURL
+			return a.Connect()
+		}, ErrConnection},
+		{"token rejected", func(a *Astro) error {
+			return rejectSubscription(a, `{"message":"Unauthorized access, invalid token","code":"E401"}`)
+		}, ErrAuth},
+		{"subscription rejected", func(a *Astro) error {
+			return rejectSubscription(a, `{"message":"unknown topic","type":"error"}`)
+		}, ErrSubscription},
+		{"subscribe timeout", func(a *Astro) error {
+			return ErrSubscribeTimeout
+		}, ErrSubscription},
+		{"invalid tip", func(a *Astro) error {
+			return a.handleTipMessage(Message{Type: "message", Topic: TipsTopic, Data: json.RawMessage(`[1]`)})
+		}, ErrInvalidMessage},
+		{"printer failed", func(a *Astro) error {
+			a.AddPrinter("jammed", &failingPrinter{})
+			a.cfg.DefaultPrinter = "jammed"
+			return a.TestPrint()
+		}, ErrPrinter},
+		{"no printer", func(a *Astro) error {
+			a.cfg.DefaultPrinter = "missing"
+			return a.TestPrint()
+		}, ErrPrinter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAstro(t, func(cfg *config.Config) { cfg.SeJWTToken = testJWT() })
+			checkClass(t, tt.run(a), tt.want)
+		})
+	}
+}
+
+// rejectSubscription waits for the response to a subscribe request that
+// Astro answers with data.
+func rejectSubscription(a *Astro, data string) error {
+	wait := a.awaitResponse("n1")
+	a.resolveWaiter(Message{Type: "response", Nonce: "n1", Data: json.RawMessage(data)})
+	_, err := wait(context.Background())
+	return err
+}
@@ -0,0 +1,31 @@
+package streamelements
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// TipEventSchema is the version of the TipEvent JSON shape. Bump it whenever
+// fields are changed or removed so consumers can branch on it.
+const TipEventSchema = 1
+
+// TipEvent is the canonical envelope for a tip as it is forwarded to the
+// webhook, written to the tip log and published on Events.
+type TipEvent struct {
+	Schema     int       `json:"schema"`
+	Topic      string    `json:"topic"`
+	ReceivedAt time.Time `json:"receivedAt"`
+	Donation   *Donation `json:"donation"`
+}
+
+// NewTipEvent wraps d, received on topic at receivedAt.
+func NewTipEvent(topic string, d *Donation, receivedAt time.Time) TipEvent {
+	return TipEvent{Schema: TipEventSchema, Topic: topic, ReceivedAt: receivedAt, Donation: d}
+}
+
+// MarshalJSON always writes the current schema version.
+func (e TipEvent) MarshalJSON() ([]byte, error) {
+	type plain TipEvent
+	e.Schema = TipEventSchema
+	return json.Marshal(plain(e))
+}
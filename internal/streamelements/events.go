@@ -0,0 +1,76 @@
+package streamelements
+
+import "slices"
+
+// eventBuffer is how many tips each event subscriber buffers when it is slow.
+const eventBuffer = 100
+
+// recentTipsMax is how many handled tips RecentTips remembers.
+const recentTipsMax = 100
+
+// Events returns a channel that receives every handled tip, for programs that
+// embed Astro and react to tips themselves. Tips are still printed as usual;
+// pass a nil printer to NewAstro to only consume events. If the consumer falls
+// more than eventBuffer tips behind, new tips are dropped from the channel
+// rather than blocking the connection. Every call returns the same channel;
+// use SubscribeEvents for independent consumers.
+func (a *Astro) Events() <-chan TipEvent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.events == nil {
+		a.events = make(chan TipEvent, eventBuffer)
+		a.eventSubs = append(a.eventSubs, a.events)
+	}
+	return a.events
+}
+
+// SubscribeEvents returns a new channel that receives every handled tip like
+// Events. Each subscriber has its own buffer, so a slow one only drops its
+// own events. Call cancel once done; the channel is then closed.
+func (a *Astro) SubscribeEvents() (events <-chan TipEvent, cancel func()) {
+	ch := make(chan TipEvent, eventBuffer)
+
+	a.mu.Lock()
+	a.eventSubs = append(a.eventSubs, ch)
+	a.mu.Unlock()
+
+	return ch, func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if i := slices.Index(a.eventSubs, ch); i >= 0 {
+			a.eventSubs = slices.Delete(a.eventSubs, i, i+1)
+			close(ch)
+		}
+	}
+}
+
+// RecentTips returns up to n of the most recently handled tips, newest first.
+func (a *Astro) RecentTips(n int) []TipEvent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	n = min(max(n, 0), len(a.recent))
+	tips := slices.Clone(a.recent[len(a.recent)-n:])
+	slices.Reverse(tips)
+	return tips
+}
+
+// publish records ev as a recent tip and sends it to every event subscriber.
+func (a *Astro) publish(ev TipEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.recent = append(a.recent, ev)
+	if len(a.recent) > recentTipsMax {
+		a.recent = slices.Delete(a.recent, 0, len(a.recent)-recentTipsMax)
+	}
+
+	for _, ch := range a.eventSubs {
+		select {
+		case ch <- ev:
+		default:
+			a.logger.Warn("event consumer is falling behind, dropping tip event", "tip_id", ev.Donation.TipID)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package streamelements
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Message filter modes, selected by MESSAGE_FILTER_MODE.
+const (
+	FilterRedact   = "redact"   // replace blocked words with asterisks
+	FilterSuppress = "suppress" // drop the whole message
+)
+
+// urlPattern matches links, including bare domains such as example.com/x.
+var urlPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+|\b[a-z0-9-]+(?:\.[a-z0-9-]+)*\.(?:com|net|org|io|gg|tv|me|ly|co|xyz|info|link|live|app)\b\S*`)
+
+// leetspeak maps look-alike characters to the letters they stand for.
+var leetspeak = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b',
+	'@': 'a', '$': 's', '!': 'i', '|': 'l',
+}
+
+// isWordRune reports whether r can be part of a word for the blocklist,
+// counting leetspeak symbols.
+func isWordRune(r rune) bool {
+	_, leet := leetspeak[r]
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || leet
+}
+
+// normalizeWord lowercases word and undoes leetspeak, so "H3LL0" matches
+// "hello".
+func normalizeWord(word string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(word) {
+		if l, ok := leetspeak[r]; ok {
+			r = l
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// filterMessage applies MESSAGE_BLOCKLIST and STRIP_MESSAGE_URLS to a tip
+// message before it is printed or spoken. In suppress mode a message with a
+// blocked word becomes "". The original message is left untouched in d.
+func (a *Astro) filterMessage(msg string) string {
+	if a.cfg.StripMessageURLs {
+		msg = strings.Join(strings.Fields(urlPattern.ReplaceAllString(msg, "")), " ")
+	}
+	if len(a.cfg.MessageBlocklist) == 0 {
+		return msg
+	}
+
+	var b strings.Builder
+	runes := []rune(msg)
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(runes) && isWordRune(runes[j]) {
+			j++
+		}
+		word := string(runes[i:j])
+		if a.blocked(word) {
+			if a.cfg.MessageFilterMode == FilterSuppress {
+				return ""
+			}
+			word = strings.Repeat("*", j-i)
+		}
+		b.WriteString(word)
+		i = j
+	}
+	return b.String()
+}
+
+// blocked reports whether word, after normalization, is on the blocklist.
+func (a *Astro) blocked(word string) bool {
+	w := normalizeWord(word)
+	return slices.ContainsFunc(a.cfg.MessageBlocklist, func(b string) bool {
+		return normalizeWord(strings.TrimSpace(b)) == w
+	})
+}
@@ -0,0 +1,42 @@
+package streamelements
+
+import (
+	"math/rand/v2"
+	"sync"
+)
+
+// footerPicker chooses the thank-you line printed at the end of each tip
+// receipt from FOOTER_MESSAGES, at random or in turn.
+type footerPicker struct {
+	messages []string
+	rotate   bool
+
+	mu   sync.Mutex
+	rnd  *rand.Rand // random choices; tests seed it for reproducible output
+	next int        // next message when rotating
+}
+
+func newFooterPicker(messages []string, mode string) *footerPicker {
+	return &footerPicker{
+		messages: messages,
+		rotate:   mode == "rotate",
+		rnd:      rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+	}
+}
+
+// pick returns the footer for the next receipt, "" if there are no messages.
+func (f *footerPicker) pick() string {
+	if len(f.messages) == 0 {
+		return ""
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.rotate {
+		msg := f.messages[f.next]
+		f.next = (f.next + 1) % len(f.messages)
+		return msg
+	}
+	return f.messages[f.rnd.IntN(len(f.messages))]
+}
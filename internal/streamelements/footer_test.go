@@ -0,0 +1,42 @@
+package streamelements
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestFooterPicker(t *testing.T) {
+	messages := []string{"a", "b", "c"}
+	picks := func(f *footerPicker, n int) []string {
+		var got []string
+		for range n {
+			got = append(got, f.pick())
+		}
+		return got
+	}
+
+	t.Run("random", func(t *testing.T) {
+		f := newFooterPicker(messages, "random")
+		f.rnd = rand.New(rand.NewPCG(1, 2))
+		want := []string{"c", "b", "c", "c", "a", "a", "b", "b", "a", "a"}
+		if got := picks(f, 10); !slices.Equal(got, want) {
+			t.Errorf("picked %q, want %q", got, want)
+		}
+	})
+
+	t.Run("rotate", func(t *testing.T) {
+		f := newFooterPicker(messages, "rotate")
+		if got, want := picks(f, 5), []string{"a", "b", "c", "a", "b"}; !slices.Equal(got, want) {
+			t.Errorf("picked %q, want %q", got, want)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		for _, mode := range []string{"random", "rotate"} {
+			if got := newFooterPicker(nil, mode).pick(); got != "" {
+				t.Errorf("%s picked %q from no messages, want \"\"", mode, got)
+			}
+		}
+	})
+}
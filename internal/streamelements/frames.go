@@ -0,0 +1,120 @@
+package streamelements
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/DaniruKun/tipfax/internal/metrics"
+)
+
+// frameTypes are the frame types Astro is known to send. Others are counted
+// as unknown.
+var frameTypes = makeSet([]string{"welcome", "response", "message"})
+
+// maxUnknownFrameTypes bounds how many distinct unknown frame types are
+// remembered.
+const maxUnknownFrameTypes = 100
+
+// FrameStats counts the frames read from Astro since startup, to diagnose
+// odd feeds.
+type FrameStats struct {
+	Total   int            `json:"total"`
+	ByType  map[string]int `json:"byType"`
+	ByTopic map[string]int `json:"byTopic"` // frames carrying a topic, mostly notifications
+	Unknown int            `json:"unknown"` // frames of a type not in frameTypes
+
+	// UnknownTypes are the distinct unknown types seen, in the order they
+	// first arrived, e.g. to report new event kinds to StreamElements.
+	UnknownTypes []string `json:"unknownTypes,omitempty"`
+}
+
+// frameStats accumulates FrameStats.
+type frameStats struct {
+	mu      sync.Mutex
+	total   int
+	byType  map[string]int
+	byTopic map[string]int
+	unknown []string
+}
+
+// record counts msg. It reports whether msg's type is unknown and wasn't
+// seen before.
+func (s *frameStats) record(msg Message) (newUnknown bool) {
+	typeLabel, topicLabel := msg.Type, msg.Topic
+	if !frameTypes[msg.Type] {
+		typeLabel = "unknown"
+	}
+	if topicLabel != TipsTopic && topicLabel != TipsModerationTopic && topicLabel != "" {
+		topicLabel = "other"
+	}
+	metrics.FramesReceived.WithLabelValues(typeLabel, topicLabel).Inc()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.byType == nil {
+		s.byType = make(map[string]int)
+		s.byTopic = make(map[string]int)
+	}
+	s.total++
+	s.byType[msg.Type]++
+	if msg.Topic != "" {
+		s.byTopic[msg.Topic]++
+	}
+
+	if frameTypes[msg.Type] || slices.Contains(s.unknown, msg.Type) || len(s.unknown) >= maxUnknownFrameTypes {
+		return false
+	}
+	s.unknown = append(s.unknown, msg.Type)
+	return true
+}
+
+func (s *frameStats) snapshot() FrameStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unknown := 0
+	for typ, n := range s.byType {
+		if !frameTypes[typ] {
+			unknown += n
+		}
+	}
+	return FrameStats{
+		Total:        s.total,
+		ByType:       maps.Clone(s.byType),
+		ByTopic:      maps.Clone(s.byTopic),
+		Unknown:      unknown,
+		UnknownTypes: slices.Clone(s.unknown),
+	}
+}
+
+// FrameStats returns the counts of frames read from Astro since startup.
+func (a *Astro) FrameStats() FrameStats {
+	return a.frames.snapshot()
+}
+
+// RunFrameStatsLog logs the frame counts every FrameStatsInterval until ctx
+// is cancelled. It returns immediately if no interval is set.
+func (a *Astro) RunFrameStatsLog(ctx context.Context) {
+	if a.cfg.FrameStatsInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(a.cfg.FrameStatsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s := a.frames.snapshot()
+		a.logger.Info("frame stats", "total", s.Total, "by_type", s.ByType, "by_topic", s.ByTopic,
+			"unknown", s.Unknown, "unknown_types", s.UnknownTypes)
+	}
+}
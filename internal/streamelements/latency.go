@@ -0,0 +1,87 @@
+package streamelements
+
+import (
+	"sync"
+	"time"
+
+	"github.com/DaniruKun/tipfax/internal/metrics"
+)
+
+// PrintLatency summarizes how long printed tips took, in milliseconds, since
+// the process started. QueueWait runs from when a tip was received (or
+// approved, for moderated tips) until its receipt started printing, and
+// includes queueing, rate limiting and retries. Print is the time the
+// printer took for the receipt, up to the cut.
+type PrintLatency struct {
+	Prints int `json:"prints"`
+
+	LastQueueWaitMs float64 `json:"lastQueueWaitMs"`
+	LastPrintMs     float64 `json:"lastPrintMs"`
+	LastTotalMs     float64 `json:"lastTotalMs"`
+
+	AvgQueueWaitMs float64 `json:"avgQueueWaitMs"`
+	AvgPrintMs     float64 `json:"avgPrintMs"`
+	AvgTotalMs     float64 `json:"avgTotalMs"`
+	MaxTotalMs     float64 `json:"maxTotalMs"`
+}
+
+// latencyStats accumulates PrintLatency.
+type latencyStats struct {
+	mu                   sync.Mutex
+	prints               int
+	lastQueue, lastPrint time.Duration
+	sumQueue, sumPrint   time.Duration
+	maxTotal             time.Duration
+}
+
+func (s *latencyStats) record(queueWait, print time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prints++
+	s.lastQueue, s.lastPrint = queueWait, print
+	s.sumQueue += queueWait
+	s.sumPrint += print
+	s.maxTotal = max(s.maxTotal, queueWait+print)
+}
+
+func (s *latencyStats) snapshot() *PrintLatency {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.prints == 0 {
+		return nil
+	}
+	n := float64(s.prints)
+	return &PrintLatency{
+		Prints:          s.prints,
+		LastQueueWaitMs: ms(s.lastQueue),
+		LastPrintMs:     ms(s.lastPrint),
+		LastTotalMs:     ms(s.lastQueue + s.lastPrint),
+		AvgQueueWaitMs:  ms(s.sumQueue) / n,
+		AvgPrintMs:      ms(s.sumPrint) / n,
+		AvgTotalMs:      ms(s.sumQueue+s.sumPrint) / n,
+		MaxTotalMs:      ms(s.maxTotal),
+	}
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// recordLatency notes how long d waited before printing on st started at
+// start, and how long the print took.
+func (a *Astro) recordLatency(st *station, d *Donation, start time.Time, print time.Duration) {
+	if d.receivedAt.IsZero() {
+		return // e.g. a test print
+	}
+	queueWait := max(start.Sub(d.receivedAt), 0)
+
+	metrics.PrintQueueWait.WithLabelValues(st.name).Observe(queueWait.Seconds())
+	metrics.PrintDuration.WithLabelValues(st.name).Observe(print.Seconds())
+	metrics.TimeToPrint.WithLabelValues(st.name).Observe((queueWait + print).Seconds())
+	a.latency.record(queueWait, print)
+
+	a.logger.Debug("printed tip", "printer", st.name, "tip_id", d.TipID,
+		"queue_wait", queueWait, "print_time", print, "total", queueWait+print)
+}
@@ -0,0 +1,107 @@
+package streamelements
+
+import (
+	"log/slog"
+	"maps"
+	"strings"
+)
+
+// locale is the language printed receipts are written in.
+type locale struct {
+	labels map[string]string
+
+	// Separators for amounts in currencies without a CURRENCY_FORMATS entry.
+	decimalSep   string
+	thousandsSep string
+}
+
+// locales are the supported receipt languages by code. English has every
+// label; any label missing from another language is printed in English.
+var locales = map[string]locale{
+	"en": {
+		labels: map[string]string{
+			"tip_from":  "Tip from",
+			"matched":   "matched",
+			"status":    "Status",
+			"message":   "Message",
+			"summary":   "Tip summary",
+			"tips":      "Tips",
+			"total":     "Total",
+			"top_donor": "Top donor",
+		},
+		decimalSep: ".",
+	},
+	"de": {
+		labels: map[string]string{
+			"tip_from":  "Spende von",
+			"matched":   "aufgestockt auf",
+			"status":    "Status",
+			"message":   "Nachricht",
+			"summary":   "Spendenübersicht",
+			"tips":      "Spenden",
+			"total":     "Summe",
+			"top_donor": "Top-Spender",
+		},
+		decimalSep:   ",",
+		thousandsSep: ".",
+	},
+	"fr": {
+		labels: map[string]string{
+			"tip_from":  "Don de",
+			"matched":   "complété à",
+			"status":    "Statut",
+			"message":   "Message",
+			"summary":   "Résumé des dons",
+			"tips":      "Dons",
+			"total":     "Total",
+			"top_donor": "Meilleur donateur",
+		},
+		decimalSep:   ",",
+		thousandsSep: " ",
+	},
+	"es": {
+		labels: map[string]string{
+			"tip_from":  "Donación de",
+			"matched":   "aumentada a",
+			"status":    "Estado",
+			"message":   "Mensaje",
+			"summary":   "Resumen de donaciones",
+			"tips":      "Donaciones",
+			"total":     "Total",
+			"top_donor": "Mayor donante",
+		},
+		decimalSep:   ",",
+		thousandsSep: ".",
+	},
+}
+
+// lookupLocale returns the locale for a language code such as "de" or
+// "de_DE", falling back to English for unknown languages.
+func lookupLocale(lang string, logger *slog.Logger) locale {
+	code, _, _ := strings.Cut(strings.ToLower(lang), "_")
+	code, _, _ = strings.Cut(code, "-")
+	if l, ok := locales[code]; ok {
+		return l
+	}
+	logger.Warn("unsupported RECEIPT_LANGUAGE, using English", "language", lang)
+	return locales["en"]
+}
+
+// label returns the receipt label for key in the receipt language.
+func (a *Astro) label(key string) string {
+	if s, ok := a.locale.labels[key]; ok {
+		return s
+	}
+	return locales["en"].labels[key]
+}
+
+// receiptLabels returns every label in the receipt language, sanitized for
+// the printer, for use in receipt templates.
+func (a *Astro) receiptLabels() map[string]string {
+	labels := maps.Clone(locales["en"].labels)
+	maps.Copy(labels, a.locale.labels)
+	for k, v := range labels {
+		labels[k] = a.sanitize(v)
+	}
+	return labels
+}
@@ -0,0 +1,74 @@
+package streamelements
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// decodeData decodes msg's data into v, whose shape the caller expects for
+// msg's type. The error names both shapes, so payloads the gateway sends in
+// a new form are easy to spot in the logs.
+func decodeData(msg Message, v any) error {
+	if err := json.Unmarshal(msg.Data, v); err != nil {
+		return fmt.Errorf("%s message has %s data, expected %s: %w", msg.Type, jsonKind(msg.Data), expectedKind(v), err)
+	}
+	return nil
+}
+
+// logBadData logs a message whose data couldn't be decoded, with the raw
+// payload at debug level.
+func (a *Astro) logBadData(msg Message, err error) {
+	a.logger.Warn("unexpected message data shape", "type", msg.Type, "topic", msg.Topic, "nonce", msg.Nonce, "error", err)
+	a.logger.Debug("unexpected message data", "type", msg.Type, "topic", msg.Topic, "data", a.redact(msg.Data))
+}
+
+// jsonKind names the kind of JSON value in data, e.g. "object" or "array".
+func jsonKind(data []byte) string {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return "no"
+	}
+	switch data[0] {
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "boolean"
+	case 'n':
+		return "null"
+	}
+	return "number"
+}
+
+// expectedKind names the kind of JSON value that decodes into the pointer v.
+func expectedKind(v any) string {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Pointer {
+		return "unknown"
+	}
+	switch t.Elem().Kind() {
+	case reflect.Struct, reflect.Map:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	}
+	return "number"
+}
+
+// mustJSON encodes v, returning nil if it can't be encoded.
+func mustJSON(v any) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return b
+}
@@ -0,0 +1,177 @@
+package streamelements
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// MockServer is an in-memory stand-in for Astro, for integration tests. It
+// greets clients with a welcome message, acknowledges every subscribe and
+// unsubscribe request, and can push synthetic events to connected clients.
+type MockServer struct {
+	srv      *httptest.Server
+	upgrader websocket.Upgrader
+
+	mu     sync.Mutex
+	conns  map[*mockConn]bool
+	topics map[string]bool // topics any client has subscribed to
+	drop   int             // subscribe requests still to ignore
+}
+
+// mockConn serializes writes to one client connection.
+type mockConn struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func (c *mockConn) writeJSON(v any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteJSON(v)
+}
+
+// NewMockServer starts a mock Astro server. Point Config.AstroURL at URL() and
+// call Close when done.
+func NewMockServer() *MockServer {
+	m := &MockServer{
+		conns:  make(map[*mockConn]bool),
+		topics: make(map[string]bool),
+	}
+	m.srv = httptest.NewServer(http.HandlerFunc(m.serve))
+	return m
+}
+
+// URL returns the server's ws:// endpoint.
+func (m *MockServer) URL() string {
+	return "ws" + strings.TrimPrefix(m.srv.URL, "http") + "/"
+}
+
+// Subscribed reports whether any client has subscribed to topic.
+func (m *MockServer) Subscribed(topic string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.topics[topic]
+}
+
+// SendTip pushes a channel.tips message with the given payload to every
+// connected client.
+func (m *MockServer) SendTip(data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return m.Send(Message{Type: "message", Topic: TipsTopic, Data: raw})
+}
+
+// Send pushes msg to every connected client.
+func (m *MockServer) Send(msg Message) error {
+	m.mu.Lock()
+	conns := make([]*mockConn, 0, len(m.conns))
+	for c := range m.conns {
+		conns = append(conns, c)
+	}
+	m.mu.Unlock()
+
+	if len(conns) == 0 {
+		return errors.New("mock server: no connected clients")
+	}
+
+	var errs []error
+	for _, c := range conns {
+		errs = append(errs, c.writeJSON(msg))
+	}
+	return errors.Join(errs...)
+}
+
+// Close disconnects all clients and shuts the server down.
+func (m *MockServer) Close() {
+	m.mu.Lock()
+	for c := range m.conns {
+		c.conn.Close()
+	}
+	m.mu.Unlock()
+	m.srv.Close()
+}
+
+// DropSubscribes makes the server silently ignore the next n subscribe
+// requests, as if they were lost.
+func (m *MockServer) DropSubscribes(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.drop = n
+}
+
+func (m *MockServer) serve(w http.ResponseWriter, r *http.Request) {
+	conn, err := m.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &mockConn{conn: conn}
+
+	m.mu.Lock()
+	m.conns[c] = true
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.conns, c)
+		m.mu.Unlock()
+		conn.Close()
+	}()
+
+	welcome := Message{Type: "welcome", Data: mustJSON(map[string]any{
+		"client_id": uuid.New().String(),
+		"message":   "You have been successfully connected",
+	})}
+	if err := c.writeJSON(welcome); err != nil {
+		return
+	}
+
+	for {
+		var req struct {
+			Type  string `json:"type"`
+			Nonce string `json:"nonce"`
+			Data  struct {
+				Topic string `json:"topic"`
+			} `json:"data"`
+		}
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+
+		var text string
+		switch req.Type {
+		case "subscribe":
+			m.mu.Lock()
+			dropped := m.drop > 0
+			if dropped {
+				m.drop--
+			} else {
+				m.topics[req.Data.Topic] = true
+			}
+			m.mu.Unlock()
+			if dropped {
+				continue
+			}
+			text = "successfully subscribed to topic"
+		case "unsubscribe":
+			text = "successfully unsubscribed from topic"
+		default:
+			continue
+		}
+
+		resp := Message{Type: "response", Nonce: req.Nonce, Data: mustJSON(map[string]any{
+			"message": text,
+			"topic":   req.Data.Topic,
+		})}
+		if err := c.writeJSON(resp); err != nil {
+			return
+		}
+	}
+}
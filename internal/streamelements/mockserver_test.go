@@ -0,0 +1,95 @@
+package streamelements
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DaniruKun/tipfax/internal/config"
+)
+
+// TestMockServerTips drives Connect, SubscribeTips and Listen against
+// MockServer and checks which pushed tips make it through handleTipMessage.
+func TestMockServerTips(t *testing.T) {
+	tip := func(id string, amount float64) map[string]any {
+		return map[string]any{
+			"_id":      id,
+			"provider": "paypal",
+			"status":   "success",
+			"donation": map[string]any{
+				"user":     map[string]any{"username": "Alice"},
+				"amount":   amount,
+				"currency": "USD",
+				"message":  "hi",
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		send     []any
+		wantTips []string // IDs of the tips published, newest first
+	}{
+		{"one tip", []any{tip("t1", 5)}, []string{"t1"}},
+		{"two tips", []any{tip("t1", 5), tip("t2", 10)}, []string{"t2", "t1"}},
+		{"duplicate", []any{tip("t1", 5), tip("t1", 5), tip("t2", 10)}, []string{"t2", "t1"}},
+		{"malformed data", []any{"not a tip", []int{1, 2}, tip("t1", 5)}, []string{"t1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockServer()
+			defer mock.Close()
+
+			a := newTestAstro(t, func(cfg *config.Config) {
+				cfg.AstroURL = mock.URL()
+				cfg.SeJWTToken = testJWT()
+			})
+			if err := a.Connect(); err != nil {
+				t.Fatalf("Connect: %v", err)
+			}
+			defer a.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			listened := make(chan error, 1)
+			go func() { listened <- a.Listen(ctx) }()
+
+			if err := a.SubscribeTips(ctx); err != nil {
+				t.Fatalf("SubscribeTips: %v", err)
+			}
+			if !mock.Subscribed(TipsTopic) {
+				t.Fatalf("mock server saw no %s subscription", TipsTopic)
+			}
+
+			for _, data := range tt.send {
+				if err := mock.SendTip(data); err != nil {
+					t.Fatalf("SendTip: %v", err)
+				}
+			}
+
+			// The last tip sent is always published, so once it shows up
+			// everything before it has been handled.
+			want := tt.wantTips
+			deadline := time.Now().Add(2 * time.Second)
+			for len(a.RecentTips(10)) < len(want) || a.RecentTips(1)[0].Donation.TipID != want[0] {
+				if time.Now().After(deadline) {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			got := a.RecentTips(10)
+			if len(got) != len(want) {
+				t.Fatalf("published %d tips, want %d", len(got), len(want))
+			}
+			for i, ev := range got {
+				if ev.Donation.TipID != want[i] {
+					t.Errorf("tip %d is %q, want %q", i, ev.Donation.TipID, want[i])
+				}
+			}
+
+			cancel()
+			<-listened
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package streamelements
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ModerationAction is the decision a moderator made about a tip.
+type ModerationAction string
+
+const (
+	ModerationPending  ModerationAction = "pending"
+	ModerationApproved ModerationAction = "approved"
+	ModerationDenied   ModerationAction = "denied"
+)
+
+// ModerationEvent is a parsed message from the channel.tips.moderation topic.
+type ModerationEvent struct {
+	TipID  string           `json:"tipId"`
+	Action ModerationAction `json:"action"`
+}
+
+// moderationEvent mirrors the wire format of a moderation message payload.
+// The tip ID and the decision have been seen under several keys, so all of
+// them are read.
+type moderationEvent struct {
+	TipID      flexID `json:"tipId"`
+	DonationID flexID `json:"donationId"`
+	ID         flexID `json:"_id"`
+	Tip        *struct {
+		ID flexID `json:"_id"`
+	} `json:"tip"`
+	Action   string `json:"action"`
+	Status   string `json:"status"`
+	Approved string `json:"approved"`
+}
+
+// ParseModeration decodes the data payload of a channel.tips.moderation message.
+func ParseModeration(data json.RawMessage) (*ModerationEvent, error) {
+	var ev moderationEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return nil, fmt.Errorf("decode moderation event: %w", err)
+	}
+
+	var nested flexID
+	if ev.Tip != nil {
+		nested = ev.Tip.ID
+	}
+	id := firstID(ev.TipID, ev.DonationID, nested, ev.ID)
+	if id == "" {
+		return nil, errors.New("moderation event has no tip ID")
+	}
+
+	var raw string
+	for _, v := range []string{ev.Action, ev.Status, ev.Approved} {
+		if v != "" {
+			raw = v
+			break
+		}
+	}
+	action, err := parseModerationAction(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ModerationEvent{TipID: id, Action: action}, nil
+}
+
+func parseModerationAction(s string) (ModerationAction, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "pending":
+		return ModerationPending, nil
+	case "approve", "approved", "allow", "allowed", "accept", "accepted":
+		return ModerationApproved, nil
+	case "deny", "denied", "reject", "rejected":
+		return ModerationDenied, nil
+	default:
+		return "", fmt.Errorf("unknown moderation action %q", s)
+	}
+}
+
+func (a *Astro) handleModerationMessage(msg Message) error {
+	ev, err := ParseModeration(msg.Data)
+	if err != nil {
+		return classify(ErrInvalidMessage, fmt.Errorf("parse moderation data: %w", err))
+	}
+
+	a.logger.Info("moderation decision", "tip_id", ev.TipID, "action", ev.Action)
+	if a.tipStore != nil {
+		a.tipStore.SetModeration(ev.TipID, ev.Action)
+	}
+
+	if ev.Action == ModerationPending {
+		return nil
+	}
+
+	d, ok := a.takePending(ev.TipID)
+	if !ok {
+		// The decision may arrive before the tip; keep it until the tip does.
+		if !a.seenTip(ev.TipID) {
+			a.logger.Debug("moderation decision for a tip not received yet, keeping it", "tip_id", ev.TipID)
+			a.holdDecision(ev.TipID, ev.Action)
+		}
+		return nil
+	}
+
+	switch ev.Action {
+	case ModerationApproved:
+		a.logger.Info("tip approved", "tip_id", d.TipID, "username", d.Username)
+		d.Moderation = ModerationApproved
+		d.receivedAt = time.Now() // the wait for approval isn't print latency
+		return a.deliverDonation(NewTipEvent(TipsTopic, d, d.receivedAt))
+	case ModerationDenied:
+		a.logger.Info("dropping denied tip", "tip_id", d.TipID, "username", d.Username)
+	}
+	return nil
+}
+
+// pendingTip is a tip held back from the printer until it is approved.
+type pendingTip struct {
+	donation *Donation
+	expires  time.Time
+}
+
+// holdPending buffers d until a moderation decision for it arrives. Expired
+// entries are pruned on every insert so unresolved tips can't pile up.
+func (a *Astro) holdPending(d *Donation) {
+	if d.TipID == "" {
+		a.logger.Warn("pending tip has no ID and can never be approved, not printing", "username", d.Username)
+		return
+	}
+
+	now := time.Now()
+
+	// Deferred first, so it runs after the unlock.
+	defer a.savePending()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for id, p := range a.pending {
+		if now.After(p.expires) {
+			a.logger.Info("discarding unresolved pending tip", "tip_id", id, "username", p.donation.Username)
+			delete(a.pending, id)
+		}
+	}
+	a.pending[d.TipID] = pendingTip{donation: d, expires: now.Add(a.cfg.PendingTipTTL)}
+
+	a.logger.Info("holding tip until it is approved", "tip_id", d.TipID)
+}
+
+// takePending removes and returns the buffered tip with the given ID.
+func (a *Astro) takePending(id string) (*Donation, bool) {
+	a.mu.Lock()
+	p, ok := a.pending[id]
+	delete(a.pending, id)
+	a.mu.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+	a.savePending()
+	if time.Now().After(p.expires) {
+		return nil, false
+	}
+
+	return p.donation, true
+}
@@ -0,0 +1,113 @@
+package streamelements
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/DaniruKun/tipfax/internal/config"
+)
+
+func TestModeratedTipSideEffects(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   TipStatus
+		decision string // moderation action sent after the tip, if any
+		wantTips int    // tips counted and published once all is handled
+		printed  bool
+	}{
+		{"completed", TipStatusCompleted, "", 1, true},
+		{"pending", TipStatusPending, "", 0, false},
+		{"pending approved", TipStatusPending, "approved", 1, true},
+		{"pending denied", TipStatusPending, "denied", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &recordingPrinter{}
+			a := newTestAstroPrinter(t, p, func(cfg *config.Config) { cfg.PrintOnlyApproved = true })
+
+			d := testDonation("t1")
+			d.Status = tt.status
+			if err := a.handleDonation(NewTipEvent(TipsTopic, d, d.Timestamp)); err != nil {
+				t.Fatalf("handleDonation: %v", err)
+			}
+			if tt.decision != "" {
+				if got := a.Stats().Tips; got != 0 {
+					t.Fatalf("held tip counted in stats before a decision: %d", got)
+				}
+				if got := len(a.RecentTips(10)); got != 0 {
+					t.Fatalf("held tip published before a decision: %d", got)
+				}
+
+				data, _ := json.Marshal(map[string]string{"tipId": "t1", "action": tt.decision})
+				msg := Message{Type: "message", Topic: TipsModerationTopic, Data: data}
+				if err := a.handleModerationMessage(msg); err != nil {
+					t.Fatalf("handleModerationMessage: %v", err)
+				}
+			}
+
+			if got := a.Stats().Tips; got != tt.wantTips {
+				t.Errorf("stats counted %d tips, want %d", got, tt.wantTips)
+			}
+			if got := len(a.RecentTips(10)); got != tt.wantTips {
+				t.Errorf("published %d tips, want %d", got, tt.wantTips)
+			}
+			if got := p.Printed(); got != tt.printed {
+				t.Errorf("printed = %v, want %v", got, tt.printed)
+			}
+		})
+	}
+}
+
+// TestPendingTipDefaults moderates pending tips under the default config,
+// where pending isn't a printable status and PRINT_ONLY_APPROVED is off.
+func TestPendingTipDefaults(t *testing.T) {
+	tests := []struct {
+		name          string
+		subscribed    bool // to the moderation topic
+		decisionFirst bool
+		decision      string
+		wantPrinted   bool
+	}{
+		{"approved", true, false, "approved", true},
+		{"denied", true, false, "denied", false},
+		{"approved before the tip", true, true, "approved", true},
+		{"no decision", true, false, "", false},
+		{"not subscribed to moderation", false, false, "approved", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &recordingPrinter{}
+			a := newTestAstroPrinter(t, p, nil)
+			if tt.subscribed {
+				a.subs = []subscription{{topic: TipsModerationTopic, channel: a.channels[0]}}
+			}
+
+			decide := func() {
+				if tt.decision == "" {
+					return
+				}
+				data, _ := json.Marshal(map[string]string{"tipId": "t1", "action": tt.decision})
+				if err := a.handleModerationMessage(Message{Type: "message", Topic: TipsModerationTopic, Data: data}); err != nil {
+					t.Fatalf("handleModerationMessage: %v", err)
+				}
+			}
+			if tt.decisionFirst {
+				decide()
+			}
+			tip := Message{Type: "message", Topic: TipsTopic, Data: tipJSON(`"t1"`, "pending")}
+			if err := a.handleTipMessage(tip); err != nil {
+				t.Fatalf("handleTipMessage: %v", err)
+			}
+			if !tt.decisionFirst {
+				if ops := p.Ops(); len(ops) != 0 {
+					t.Fatalf("pending tip printed before a decision: %q", ops)
+				}
+				decide()
+			}
+
+			if got := p.Printed(); got != tt.wantPrinted {
+				t.Errorf("printed = %v, want %v", got, tt.wantPrinted)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package streamelements
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// requestTTL is how long a request without a response is remembered. Requests
+// are normally forgotten sooner, when answered or when waiting times out.
+const requestTTL = 5 * time.Minute
+
+// request is a subscribe or unsubscribe request waiting for its response.
+type request struct {
+	kind    string // "subscribe" or "unsubscribe"
+	topic   string
+	conn    *websocket.Conn // the connection it was sent on
+	expires time.Time
+}
+
+// newRequest registers a kind request for topic on the current connection
+// and returns its nonce. A nonce still in flight, on this connection or an
+// earlier one, is never handed out twice: a colliding one is replaced, and
+// after a few tries an error is returned.
+func (a *Astro) newRequest(kind, topic string) (string, error) {
+	now := time.Now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for nonce, req := range a.requests {
+		if now.After(req.expires) {
+			delete(a.requests, nonce)
+		}
+	}
+
+	for range 3 {
+		nonce := a.newNonce()
+		if _, inFlight := a.requests[nonce]; inFlight {
+			a.logger.Warn("nonce already in flight, making a new one", "nonce", nonce, "kind", kind, "topic", topic)
+			continue
+		}
+		a.requests[nonce] = request{kind: kind, topic: topic, conn: a.conn, expires: now.Add(requestTTL)}
+		return nonce, nil
+	}
+	return "", fmt.Errorf("%s %s: no unused nonce", kind, topic)
+}
+
+// takeRequest returns the request nonce answers and forgets it. It reports
+// false if there is none, or it was sent on an earlier connection.
+func (a *Astro) takeRequest(nonce string) (request, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	req, ok := a.requests[nonce]
+	if !ok {
+		return request{}, false
+	}
+	delete(a.requests, nonce)
+	return req, req.conn == a.conn
+}
@@ -0,0 +1,156 @@
+package streamelements
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DaniruKun/tipfax/internal/config"
+	"github.com/gorilla/websocket"
+)
+
+// fixedNonces returns a nonce generator handing out nonces in turn, then
+// repeating the last one.
+func fixedNonces(nonces ...string) func() string {
+	i := 0
+	return func() string {
+		n := nonces[min(i, len(nonces)-1)]
+		i++
+		return n
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		inFlight map[string]time.Duration // nonces already sent, by time to expiry
+		nonces   []string
+		want     string
+		wantErr  bool
+	}{
+		{"fresh", nil, []string{"a"}, "a", false},
+		{"collision replaced", map[string]time.Duration{"a": time.Minute}, []string{"a", "b"}, "b", false},
+		{"collisions exhausted", map[string]time.Duration{"a": time.Minute}, []string{"a"}, "", true},
+		{"expired nonce reused", map[string]time.Duration{"a": -time.Second}, []string{"a"}, "a", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAstro(t, nil)
+			a.newNonce = fixedNonces(tt.nonces...)
+			for nonce, ttl := range tt.inFlight {
+				a.requests[nonce] = request{kind: "subscribe", topic: TipsTopic, expires: time.Now().Add(ttl)}
+			}
+
+			got, err := a.newRequest("unsubscribe", TipsModerationTopic)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newRequest error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("newRequest = %q, want %q", got, tt.want)
+			}
+			if tt.wantErr {
+				return
+			}
+			req := a.requests[got]
+			if req.kind != "unsubscribe" || req.topic != TipsModerationTopic {
+				t.Errorf("request recorded as %s %s, want unsubscribe %s", req.kind, req.topic, TipsModerationTopic)
+			}
+			if ttl := time.Until(req.expires); ttl <= 0 || ttl > requestTTL {
+				t.Errorf("request expires in %s, want within %s", ttl, requestTTL)
+			}
+		})
+	}
+}
+
+func TestTakeRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		earlier   bool // request sent on an earlier connection
+		nonce     string
+		wantKnown bool
+	}{
+		{"answered", false, "n1", true},
+		{"unknown nonce", false, "n2", false},
+		{"earlier connection", true, "n1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAstro(t, nil)
+			a.newNonce = fixedNonces("n1")
+			if _, err := a.newRequest("subscribe", TipsTopic); err != nil {
+				t.Fatalf("newRequest: %v", err)
+			}
+			if tt.earlier {
+				a.conn = &websocket.Conn{} // a reconnect since
+			}
+
+			req, known := a.takeRequest(tt.nonce)
+			if known != tt.wantKnown {
+				t.Fatalf("takeRequest known = %v, want %v", known, tt.wantKnown)
+			}
+			if known && (req.kind != "subscribe" || req.topic != TipsTopic) {
+				t.Errorf("request is %s %s, want subscribe %s", req.kind, req.topic, TipsTopic)
+			}
+			if _, known := a.takeRequest(tt.nonce); known {
+				t.Error("request still known after it was answered")
+			}
+		})
+	}
+}
+
+// TestRequestCleanup subscribes against MockServer with predictable nonces
+// and checks requests are forgotten once answered or timed out.
+func TestRequestCleanup(t *testing.T) {
+	tests := []struct {
+		name    string
+		drop    int // subscribe requests the server ignores
+		wantErr error
+	}{
+		{"acknowledged", 0, nil},
+		{"acknowledged when resent", 1, nil},
+		{"never acknowledged", 2, ErrSubscribeTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockServer()
+			defer mock.Close()
+			mock.DropSubscribes(tt.drop)
+
+			a := newTestAstro(t, func(cfg *config.Config) {
+				cfg.AstroURL = mock.URL()
+				cfg.SeJWTToken = testJWT()
+				cfg.SubscribeTimeout = 50 * time.Millisecond
+			})
+			a.newNonce = fixedNonces("n1", "n2", "n3")
+			if err := a.Connect(); err != nil {
+				t.Fatalf("Connect: %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			listened := make(chan error, 1)
+			go func() { listened <- a.Listen(ctx) }()
+			defer func() {
+				cancel()
+				<-listened
+			}()
+
+			err := a.SubscribeTips(ctx)
+			if !errors.Is(err, tt.wantErr) || (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("SubscribeTips error = %v, want %v", err, tt.wantErr)
+			}
+
+			// The waiter is answered before Listen handles the response, so
+			// give it a moment to take the request.
+			left := -1
+			for deadline := time.Now().Add(time.Second); left != 0 && time.Now().Before(deadline); {
+				time.Sleep(5 * time.Millisecond)
+				a.mu.Lock()
+				left = len(a.requests)
+				a.mu.Unlock()
+			}
+			if left != 0 {
+				t.Errorf("%d requests still in flight", left)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package streamelements
+
+// Paused reports whether printing is paused, see SetPaused.
+func (a *Astro) Paused() bool {
+	return a.paused.Load()
+}
+
+// SetPaused pauses or resumes printing. While paused, tips are still
+// received, logged and forwarded, but queued instead of printed, as if the
+// printer were offline. Resuming prints the queued tips in order at the
+// configured print rate.
+func (a *Astro) SetPaused(on bool) {
+	if a.paused.Swap(on) == on {
+		return
+	}
+	if on {
+		a.logger.Info("printing paused, tips will be queued")
+		return
+	}
+
+	a.logger.Info("printing resumed", "queued", a.Status().PrintQueued)
+	for _, name := range a.stationOrder {
+		a.stations[name].wakeQueue()
+	}
+}
@@ -0,0 +1,89 @@
+package streamelements
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+)
+
+// savedPendingTip is the on-disk form of a pendingTip.
+type savedPendingTip struct {
+	Donation *Donation `json:"donation"`
+	Expires  time.Time `json:"expires"`
+}
+
+// savePending writes the tips awaiting approval to PENDING_TIPS_PATH, if set,
+// so approvals arriving after a restart still find them. The file is written
+// under a temporary name and renamed, so a crash never leaves it partial.
+// Failures are only logged; the tips are still held in memory.
+func (a *Astro) savePending() {
+	path := a.cfg.PendingTipsPath
+	if path == "" {
+		return
+	}
+
+	a.pendingSave.Lock()
+	defer a.pendingSave.Unlock()
+
+	a.mu.Lock()
+	saved := make([]savedPendingTip, 0, len(a.pending))
+	for _, p := range a.pending {
+		saved = append(saved, savedPendingTip{Donation: p.donation, Expires: p.expires})
+	}
+	a.mu.Unlock()
+
+	data, err := json.Marshal(saved)
+	if err == nil {
+		tmp := path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0o644); err == nil {
+			err = os.Rename(tmp, path)
+		}
+	}
+	if err != nil {
+		a.logger.Warn("failed to save pending tips", "path", path, "error", err)
+	}
+}
+
+// loadPending restores the tips saved by savePending, dropping those that
+// expired in the meantime.
+func (a *Astro) loadPending() {
+	path := a.cfg.PendingTipsPath
+	if path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	var saved []savedPendingTip
+	if err == nil {
+		err = json.Unmarshal(data, &saved)
+	}
+	if err != nil {
+		a.logger.Warn("failed to load pending tips, starting without them", "path", path, "error", err)
+		return
+	}
+
+	now := time.Now()
+	expired := 0
+	a.mu.Lock()
+	for _, p := range saved {
+		if p.Donation == nil || p.Donation.TipID == "" {
+			continue
+		}
+		if now.After(p.Expires) {
+			expired++
+			continue
+		}
+		a.pending[p.Donation.TipID] = pendingTip{donation: p.Donation, expires: p.Expires}
+	}
+	restored := len(a.pending)
+	a.mu.Unlock()
+
+	a.logger.Info("restored tips awaiting approval", "path", path, "tips", restored, "expired", expired)
+	if expired > 0 {
+		a.savePending()
+	}
+}
@@ -0,0 +1,162 @@
+package streamelements
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DaniruKun/tipfax/internal/fax"
+	"github.com/DaniruKun/tipfax/internal/metrics"
+)
+
+// printDonation prints a receipt for d on every station it is routed to. It
+// returns the errors of the stations that failed to print, which queued d.
+// Having no printer at all is only warned about once and not an error.
+func (a *Astro) printDonation(d *Donation) error {
+	stations := a.routeDonation(d)
+	if len(stations) == 0 {
+		a.noPrinterOnce.Do(func() {
+			a.logger.Warn("NO PRINTER AVAILABLE: tips are only logged, not printed. Check DEVICE_PATH, or set REQUIRE_PRINTER to fail at startup instead")
+		})
+		return nil
+	}
+
+	if matched, ok := a.matchedAmount(d); ok {
+		a.logger.Info("tip matched", "tip_id", d.TipID, "amount", d.Amount, "matched_amount", matched, "currency", d.Currency)
+	}
+
+	var errs []error
+	for _, st := range stations {
+		errs = append(errs, a.printOn(st, d))
+	}
+	return errors.Join(errs...)
+}
+
+// printOn prints a receipt for d on st, retrying transient failures. d is
+// queued instead, and printed once the printer can take it, if printing is
+// paused, earlier tips are already waiting, the printer reports an error, the
+// print rate is exceeded or the receipt still can't be printed. It returns the
+// print error if printing failed; a tip queued without trying is not an error.
+func (a *Astro) printOn(st *station, d *Donation) error {
+	if a.Paused() {
+		st.enqueue(d)
+		a.logger.Info("printing paused, queueing tip", "printer", st.name, "tip_id", d.TipID, "queued", st.queue.Len())
+		return nil
+	}
+	if st.queue.Len() > 0 {
+		st.enqueue(d)
+		return nil
+	}
+	if fault := st.fault(); fault != "" {
+		st.enqueue(d)
+		a.logger.Warn("printer in error state, queueing tip", "printer", st.name, "status", fault, "tip_id", d.TipID, "queued", st.queue.Len())
+		return nil
+	}
+	if !st.limiter.Allow() {
+		st.enqueue(d)
+		a.logger.Info("print rate exceeded, queueing tip", "printer", st.name, "tip_id", d.TipID, "queued", st.queue.Len())
+		return nil
+	}
+
+	if err := a.printWithRetry(st, d); err != nil {
+		raw, _ := json.Marshal(d)
+		a.logger.Error("failed to print receipt, queueing tip", "printer", st.name, "error", err, "tip", string(raw))
+		metrics.PrintErrors.Inc()
+		st.enqueue(d)
+		return fmt.Errorf("printer %s: %w", st.name, err)
+	}
+	return nil
+}
+
+// printWithRetry prints the receipt for d, retrying up to PrintRetries times
+// with PrintRetryDelay between attempts. The receipt is built once, so every
+// attempt prints the same footer and template output. Once it succeeds d
+// counts as printed, so a later refund of it is flagged. Both direct and
+// queued printing go through here.
+func (a *Astro) printWithRetry(st *station, d *Donation) error {
+	job := a.buildReceipt(d)
+	var err error
+	for retry := 0; retry <= a.cfg.PrintRetries; retry++ {
+		if retry > 0 {
+			a.logger.Warn("retrying print", "printer", st.name, "retry", retry, "max_retries", a.cfg.PrintRetries, "error", err)
+			time.Sleep(a.cfg.PrintRetryDelay)
+		}
+		if err = a.printReceipt(st, d, job); err == nil {
+			if d.TipID != "" {
+				a.printed.Seen(d.TipID, time.Now())
+			}
+			return nil
+		}
+	}
+	return err
+}
+
+// printReceipt prints job, the receipt for d, on st and cuts the paper, then
+// kicks the drawer and beeps if configured.
+func (a *Astro) printReceipt(st *station, d *Donation, job ReceiptJob) error {
+	start := time.Now()
+	if err := st.do(func(p fax.Printer) error {
+		return a.renderer().render(p, job)
+	}); err != nil {
+		return classify(ErrPrinter, err)
+	}
+	a.recordLatency(st, d, start, time.Since(start))
+
+	a.alertTip(st, d)
+	return nil
+}
+
+// alertTip opens the cash drawer and sounds the buzzer of st's printer for
+// tips of at least TipAlertMinAmount. Printers without a kick port or buzzer
+// are skipped. The receipt is already printed, so errors are only logged.
+func (a *Astro) alertTip(st *station, d *Donation) {
+	if !a.cfg.KickDrawerOnTip && !a.cfg.BeepOnTip {
+		return
+	}
+	if a.cfg.TipAlertMinAmount > 0 {
+		amount, ok := a.baseAmount(d)
+		if !ok {
+			amount = d.Amount
+		}
+		if amount < a.cfg.TipAlertMinAmount {
+			return
+		}
+	}
+
+	err := st.do(func(p fax.Printer) error {
+		var errs []error
+		if k, ok := p.(fax.DrawerKicker); ok && a.cfg.KickDrawerOnTip {
+			errs = append(errs, k.KickDrawer(a.cfg.DrawerPin, a.cfg.DrawerPulseOn, a.cfg.DrawerPulseOff))
+		}
+		if b, ok := p.(fax.Beeper); ok && a.cfg.BeepOnTip {
+			errs = append(errs, b.Beep(a.cfg.BeepTimes, a.cfg.BeepDuration))
+		}
+		return errors.Join(errs...)
+	})
+	if err != nil {
+		a.logger.Warn("failed to kick drawer or beep", "printer", st.name, "tip_id", d.TipID, "error", err)
+	}
+}
+
+// TestPrint prints a sample receipt on the default printer so the paper,
+// alignment, cutter and code page can be checked without a real tip.
+func (a *Astro) TestPrint() error {
+	st, ok := a.stations[a.cfg.DefaultPrinter]
+	if !ok {
+		return classify(ErrPrinter, errors.New("no printer available"))
+	}
+
+	d := &Donation{
+		TipID:     "test",
+		Username:  "TipFax",
+		Amount:    12.34,
+		Currency:  "USD",
+		Message:   "Hello from tipfax!",
+		Status:    TipStatusCompleted,
+		Provider:  "tipfax",
+		Timestamp: time.Now(),
+	}
+	a.convertDonation(d)
+	return a.printReceipt(st, d, a.buildReceipt(d))
+}
@@ -0,0 +1,93 @@
+package streamelements
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/DaniruKun/tipfax/internal/config"
+)
+
+// TestPrintedOnlyOnceOut checks a tip only counts as printed, and so only
+// has its refund flagged, once its receipt has come out.
+func TestPrintedOnlyOnceOut(t *testing.T) {
+	tests := []struct {
+		name   string
+		paused bool
+		failed bool
+		want   bool
+	}{
+		{"printed", false, false, true},
+		{"queued while paused", true, false, false},
+		{"print failed", false, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAstro(t, func(cfg *config.Config) { cfg.PrintRetries = 0 })
+			if tt.failed {
+				a.AddPrinter("jammed", &failingPrinter{})
+				a.cfg.DefaultPrinter = "jammed"
+			}
+			a.SetPaused(tt.paused)
+
+			a.printDonation(testDonation("t1"))
+			if got := a.printed.Has("t1"); got != tt.want {
+				t.Errorf("counted as printed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestQueuedTipPrintedOnDrain checks a queued tip counts as printed once the
+// queue is drained.
+func TestQueuedTipPrintedOnDrain(t *testing.T) {
+	p := &recordingPrinter{}
+	a := newTestAstroPrinter(t, p, nil)
+	a.SetPaused(true)
+	a.printDonation(testDonation("t1"))
+	if a.printed.Has("t1") || p.Printed() {
+		t.Fatal("tip printed while paused")
+	}
+
+	a.SetPaused(false)
+	a.FlushPrintQueue()
+	if !p.Printed() || !a.printed.Has("t1") {
+		t.Errorf("queued tip not printed on drain: printed %v, counted %v", p.Printed(), a.printed.Has("t1"))
+	}
+}
+
+// flakyPrinter fails its first writes, then prints normally.
+type flakyPrinter struct {
+	recordingPrinter
+	failures int
+}
+
+func (p *flakyPrinter) Write(data string) (int, error) {
+	if p.failures > 0 {
+		p.failures--
+		return 0, errors.New("paper jam")
+	}
+	return p.recordingPrinter.Write(data)
+}
+
+// TestRetryPrintsSameReceipt checks a retried print doesn't build the receipt
+// again, which would move on to the next footer.
+func TestRetryPrintsSameReceipt(t *testing.T) {
+	p := &flakyPrinter{failures: 2}
+	a := newTestAstroPrinter(t, p, func(cfg *config.Config) {
+		cfg.PrintRetries = 2
+		cfg.PrintRetryDelay = 0
+		cfg.FooterMessages = []string{"first", "second", "third"}
+		cfg.FooterMode = "rotate"
+	})
+
+	if err := a.printDonation(testDonation("t1")); err != nil {
+		t.Fatalf("printDonation: %v", err)
+	}
+	if ops := p.Ops(); !slices.Contains(ops, "first") || slices.Contains(ops, "second") || slices.Contains(ops, "third") {
+		t.Errorf("printed %q, want the first footer", ops)
+	}
+	if next := a.footer.pick(); next != "second" {
+		t.Errorf("next footer %q, want second", next)
+	}
+}
@@ -0,0 +1,82 @@
+package streamelements
+
+import (
+	"errors"
+	"time"
+
+	"github.com/DaniruKun/tipfax/internal/fax"
+)
+
+// fault returns why st's printer can't print, or "" if it can.
+func (st *station) fault() string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.faultReason
+}
+
+func (st *station) setFault(reason string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.faultReason = reason
+}
+
+// printerFaults returns the error state of every station whose printer
+// reported a problem, keyed by printer name.
+func (a *Astro) printerFaults() map[string]string {
+	var faults map[string]string
+	for name, st := range a.stations {
+		if reason := st.fault(); reason != "" {
+			if faults == nil {
+				faults = make(map[string]string)
+			}
+			faults[name] = reason
+		}
+	}
+	return faults
+}
+
+// runStatusPoll checks st's printer every PrinterStatusInterval for paper-out,
+// cover-open and cutter errors. While the printer reports one, tips for st go
+// to its queue. It returns straight away for printers that can't report
+// their status.
+func (a *Astro) runStatusPoll(st *station) {
+	sc, ok := st.printer.(fax.StatusChecker)
+	if !ok || a.cfg.PrinterStatusInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(a.cfg.PrinterStatusInterval)
+	defer ticker.Stop()
+
+	var last fax.Status
+	for range ticker.C {
+		var status fax.Status
+		err := st.do(func(fax.Printer) error {
+			var err error
+			status, err = sc.Status()
+			return err
+		})
+		if errors.Is(err, fax.ErrStatusUnsupported) {
+			a.logger.Info("printer does not report its status, not polling it", "printer", st.name)
+			return
+		}
+		if err != nil {
+			a.logger.Warn("failed to query printer status", "printer", st.name, "error", err)
+			continue
+		}
+
+		switch {
+		case !status.OK() && status != last:
+			a.logger.Error("PRINTER ERROR: tips will be queued until it is fixed", "printer", st.name, "status", status.String())
+			st.setFault(status.String())
+		case status.OK() && !last.OK():
+			a.logger.Info("printer recovered", "printer", st.name, "queued", st.queue.Len())
+			st.setFault("")
+			st.wakeQueue()
+		}
+		if status.PaperLow && !last.PaperLow {
+			a.logger.Warn("printer paper is running low", "printer", st.name)
+		}
+		last = status
+	}
+}
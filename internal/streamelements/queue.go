@@ -0,0 +1,167 @@
+package streamelements
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/DaniruKun/tipfax/internal/fax"
+)
+
+// printQueue is a bounded FIFO of tips waiting to be printed. When full, the
+// oldest tip is dropped to make room.
+type printQueue struct {
+	mu     sync.Mutex
+	items  []*Donation
+	max    int
+	logger *slog.Logger
+}
+
+func newPrintQueue(max int, logger *slog.Logger) *printQueue {
+	return &printQueue{max: max, logger: logger}
+}
+
+func (q *printQueue) Push(d *Donation) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.max > 0 && len(q.items) >= q.max {
+		dropped := q.items[0]
+		q.items = q.items[1:]
+		q.logger.Warn("print queue full, dropping oldest tip", "tip_id", dropped.TipID,
+			"username", dropped.Username, "amount", dropped.Amount, "currency", dropped.Currency)
+	}
+	q.items = append(q.items, d)
+}
+
+// PushFront puts d back at the head of the queue after a failed print.
+func (q *printQueue) PushFront(d *Donation) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.items = append([]*Donation{d}, q.items...)
+}
+
+func (q *printQueue) Pop() (*Donation, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	d := q.items[0]
+	q.items = q.items[1:]
+	return d, true
+}
+
+func (q *printQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// runPrintQueue periodically tries to bring st's printer back and drain its
+// queue. A wakeup on st.wake triggers an immediate attempt. Nothing is printed
+// while the printer reports an error or printing is paused.
+func (a *Astro) runPrintQueue(st *station) {
+	ticker := time.NewTicker(a.cfg.PrintQueueRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-st.wake:
+		}
+
+		if st.queue.Len() == 0 || st.fault() != "" || a.Paused() {
+			continue
+		}
+		if !a.drainPrintQueue(st, true) && !a.Paused() {
+			if r, ok := st.printer.(fax.Reopener); ok {
+				err := st.do(func(fax.Printer) error { return r.Reopen() })
+				if err != nil {
+					a.logger.Warn("printer still unavailable", "printer", st.name, "queued", st.queue.Len(), "error", err)
+					continue
+				}
+				a.logger.Info("printer reopened", "printer", st.name)
+				a.drainPrintQueue(st, true)
+			}
+		}
+	}
+}
+
+// drainPrintQueue prints st's queued tips in order until the queue is empty
+// or a print fails. If limited, it paces prints to st's print rate and stops
+// when printing is paused. It reports whether the queue was emptied.
+func (a *Astro) drainPrintQueue(st *station, limited bool) bool {
+	return a.drainPrintQueueContext(context.Background(), st, limited)
+}
+
+// drainPrintQueueContext is like drainPrintQueue but also stops, before the
+// next tip, once ctx is done.
+func (a *Astro) drainPrintQueueContext(ctx context.Context, st *station, limited bool) bool {
+	for {
+		if st.queue.Len() == 0 {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		if limited {
+			st.limiter.Wait()
+			if a.Paused() {
+				return false
+			}
+		}
+
+		d, ok := st.queue.Pop()
+		if !ok {
+			return true
+		}
+		if err := a.printWithRetry(st, d); err != nil {
+			st.queue.PushFront(d)
+			return false
+		}
+		a.logger.Info("printed queued tip", "printer", st.name, "tip_id", d.TipID, "username", d.Username, "remaining", st.queue.Len())
+	}
+}
+
+// FlushPrintQueue makes a final attempt to print all queued tips, e.g. during
+// shutdown, after printing any held by the anti-spam guard. Tips that still
+// can't be printed, or are queued while printing is paused, are logged.
+func (a *Astro) FlushPrintQueue() {
+	a.flushPrintQueue(context.Background())
+}
+
+// flushPrintQueue is FlushPrintQueue, giving up on the remaining tips once
+// ctx is done.
+func (a *Astro) flushPrintQueue(ctx context.Context) {
+	if a.spam != nil {
+		a.spam.flushAll()
+	}
+	for _, name := range a.stationOrder {
+		st := a.stations[name]
+		if st.queue.Len() == 0 {
+			continue
+		}
+
+		if fault := st.fault(); fault != "" {
+			a.logger.Warn("printer in error state, not flushing print queue", "printer", st.name, "status", fault)
+		} else if a.Paused() {
+			a.logger.Warn("printing paused, not flushing print queue", "printer", st.name, "queued", st.queue.Len())
+		} else {
+			a.logger.Info("flushing print queue", "printer", st.name, "queued", st.queue.Len())
+			if a.drainPrintQueueContext(ctx, st, false) {
+				continue
+			}
+		}
+		for {
+			d, ok := st.queue.Pop()
+			if !ok {
+				break
+			}
+			a.logger.Error("unprinted tip", "printer", st.name, "tip_id", d.TipID, "username", d.Username,
+				"amount", d.Amount, "currency", d.Currency, "message", d.Message)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package streamelements
+
+import (
+	"sync"
+	"time"
+)
+
+// tokenBucket limits how often receipts are printed. It holds up to burst
+// tokens and refills at rate tokens per second.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a full bucket allowing perMinute prints per minute
+// with bursts of up to burst. It returns nil, meaning unlimited, if perMinute
+// is not positive.
+func newTokenBucket(perMinute, burst int) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	burst = max(burst, 1)
+	return &tokenBucket{
+		rate:   float64(perMinute) / 60,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Allow takes a token if one is available. A nil bucket always allows.
+func (b *tokenBucket) Allow() bool {
+	return b.take(time.Now()) == 0
+}
+
+// Wait blocks until a token is available and takes it.
+func (b *tokenBucket) Wait() {
+	for {
+		wait := b.take(time.Now())
+		if wait == 0 {
+			return
+		}
+		time.Sleep(wait)
+	}
+}
+
+// take takes a token and returns 0, or returns how long until one is
+// available.
+func (b *tokenBucket) take(now time.Time) time.Duration {
+	if b == nil {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
@@ -0,0 +1,128 @@
+package streamelements
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// readerBuffer is how many messages a connection's reader holds while nobody
+// is listening yet, e.g. between Connect and Listen.
+const readerBuffer = 64
+
+// connReader reads messages from one connection in the background, so
+// subscription responses are seen before Listen starts.
+type connReader struct {
+	msgs     chan Message
+	errs     chan error
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// subscribeResult is the outcome of a subscribe request, as reported by the
+// response carrying its nonce.
+type subscribeResult struct {
+	ok      bool
+	message string
+	room    string // channel ID the subscription is for
+}
+
+// startReader starts reading from conn. Responses matching a pending
+// subscribe request are delivered to its waiter; every message is also
+// queued for Listen. Each message extends the read deadline by readTimeout.
+func (a *Astro) startReader(conn *websocket.Conn, readTimeout time.Duration) *connReader {
+	r := &connReader{
+		msgs: make(chan Message, readerBuffer),
+		errs: make(chan error, 1),
+		stop: make(chan struct{}),
+	}
+
+	go func() {
+		for {
+			var msg Message
+			if err := conn.ReadJSON(&msg); err != nil {
+				if isTimeout(err) {
+					err = fmt.Errorf("%w for %s: %w", ErrReadTimeout, readTimeout, err)
+				}
+				r.errs <- err
+				return
+			}
+			conn.SetReadDeadline(time.Now().Add(readTimeout))
+			if msg.Type == "response" {
+				a.resolveWaiter(msg)
+			}
+			select {
+			case r.msgs <- msg:
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+	return r
+}
+
+// close stops the reader once its connection is closed.
+func (r *connReader) close() {
+	r.stopOnce.Do(func() { close(r.stop) })
+}
+
+// resolveWaiter hands a response to the subscribe call waiting for its nonce.
+func (a *Astro) resolveWaiter(msg Message) {
+	// Responses that aren't objects are reported by handleMessage.
+	var data map[string]any
+	decodeData(msg, &data)
+	ok, message := classifyResponse(data)
+	room, _ := data["room"].(string)
+
+	a.mu.Lock()
+	w, found := a.waiters[msg.Nonce]
+	delete(a.waiters, msg.Nonce)
+	a.mu.Unlock()
+
+	if found {
+		w <- subscribeResult{ok: ok, message: message, room: room}
+	}
+}
+
+// awaitResponse registers interest in the response to nonce. The returned
+// wait function blocks until it arrives or ctx is done, and returns the room
+// of an accepted subscription.
+func (a *Astro) awaitResponse(nonce string) func(ctx context.Context) (string, error) {
+	w := make(chan subscribeResult, 1)
+	a.mu.Lock()
+	a.waiters[nonce] = w
+	a.mu.Unlock()
+
+	return func(ctx context.Context) (string, error) {
+		select {
+		case res := <-w:
+			if !res.ok {
+				class := ErrSubscription
+				if isAuthError(res.message) {
+					class = ErrAuth
+				}
+				return "", classify(class, fmt.Errorf("rejected by Astro: %s", res.message))
+			}
+			return res.room, nil
+		case <-ctx.Done():
+			a.dropWaiter(nonce)
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return "", fmt.Errorf("%w: %w", ErrSubscribeTimeout, ctx.Err())
+			}
+			return "", fmt.Errorf("no response from Astro: %w", ctx.Err())
+		}
+	}
+}
+
+// dropWaiter forgets the waiter and the request for nonce, e.g. when the
+// request couldn't be sent or timed out.
+func (a *Astro) dropWaiter(nonce string) {
+	a.mu.Lock()
+	delete(a.waiters, nonce)
+	delete(a.requests, nonce)
+	a.mu.Unlock()
+}
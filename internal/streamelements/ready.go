@@ -0,0 +1,40 @@
+package streamelements
+
+import (
+	"time"
+
+	"github.com/DaniruKun/tipfax/internal/fax"
+)
+
+// announceReady is called once tips are subscribed, at startup or after a
+// reconnect. The first time it logs that tipfax is ready and, with
+// PRINT_READY_RECEIPT, prints a notice; reconnects print it again only with
+// PRINT_ON_RECONNECT, so a flaky network doesn't use up the paper.
+func (a *Astro) announceReady() {
+	first := !a.wasReady.Swap(true)
+	if first {
+		a.logger.Info("subscribed and ready, listening for tips")
+	}
+	if !a.cfg.PrintReadyReceipt || (!first && !a.cfg.PrintOnReconnect) {
+		return
+	}
+
+	st, ok := a.stations[a.cfg.DefaultPrinter]
+	if !ok {
+		return
+	}
+	job := a.newReceiptJob([]string{
+		"TipFax ready",
+		"Listening for tips",
+		time.Now().Format("2006-01-02 15:04:05"),
+	})
+	// Print in the background so a slow printer doesn't hold up listening.
+	go func() {
+		err := st.do(func(p fax.Printer) error {
+			return a.renderer().render(p, job)
+		})
+		if err != nil {
+			a.logger.Error("failed to print ready notice", "error", err)
+		}
+	}()
+}
@@ -0,0 +1,266 @@
+package streamelements
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// defaultReceiptTemplate is used when no ReceiptTemplate is configured or the
+// configured one can't be used.
+const defaultReceiptTemplate = `{{.Labels.tip_from}} {{.Username}}: {{.FormattedAmount}}{{if .Collapsed}} (x{{.Collapsed}}){{end}}
+{{if .Matched}}{{.FormattedAmount}} -> {{.Labels.matched}} {{.FormattedMatched}}!
+{{end}}{{if .Converted}}= {{.FormattedConverted}}
+{{end}}{{.Labels.status}}: {{.Status}}
+{{if .Message}}{{.Labels.message}}: {{.Message}}
+{{end}}`
+
+var defaultReceipt = template.Must(template.New("receipt").Parse(defaultReceiptTemplate))
+
+// receiptData holds the fields available to receipt templates. Text fields
+// are already sanitized for the printer.
+type receiptData struct {
+	Username      string
+	Amount        string
+	Currency      string
+	Message       string
+	Status        string
+	Provider      string
+	TipID         string
+	Channel       string
+	Timestamp     string
+	Matched       bool
+	MatchedAmount string
+
+	// Converted is set if Currency differs from BaseCurrency and a rate is
+	// known, in which case ConvertedAmount is the amount in BaseCurrency.
+	Converted       bool
+	ConvertedAmount string
+	BaseCurrency    string
+
+	// Amounts formatted per CURRENCY_FORMATS, e.g. "$12.34".
+	FormattedAmount    string
+	FormattedMatched   string
+	FormattedConverted string
+
+	// Donor totals for this session, including this tip. DonorTips is 0 for
+	// anonymous tips; RepeatDonor is set from the donor's second tip on.
+	DonorTips           int
+	DonorOrdinal        string // DonorTips as an English ordinal, e.g. "3rd"
+	RepeatDonor         bool
+	DonorTotal          string // in BaseCurrency where rates allow
+	FormattedDonorTotal string
+
+	// Collapsed is the number of identical tips from one donor this receipt
+	// stands for, with Amount their total, see SPAM_WINDOW. It is 0 for an
+	// ordinary tip.
+	Collapsed int
+
+	// Labels are the receipt labels in RECEIPT_LANGUAGE, by key, e.g.
+	// {{.Labels.tip_from}}.
+	Labels map[string]string
+}
+
+// parseReceiptTemplate parses a user-supplied receipt template. A literal
+// "\n" is accepted as a line break so templates fit in an environment
+// variable. It returns nil, meaning "use the default", if text is empty or
+// invalid.
+func parseReceiptTemplate(text string, logger *slog.Logger) *template.Template {
+	if text == "" {
+		return nil
+	}
+
+	t, err := template.New("receipt").Parse(strings.ReplaceAll(text, `\n`, "\n"))
+	if err != nil {
+		logger.Warn("invalid RECEIPT_TEMPLATE, using the default receipt", "error", err)
+		return nil
+	}
+	return t
+}
+
+// newReceiptData collects the template fields for d.
+func (a *Astro) newReceiptData(d *Donation) receiptData {
+	matched, isMatched := a.matchedAmount(d)
+
+	return receiptData{
+		Username:      a.sanitizeLine(d.Username),
+		Amount:        fmt.Sprintf("%.2f", d.Amount),
+		Currency:      a.sanitizeLine(d.Currency),
+		Message:       a.sanitizeMessage(a.filterMessage(d.Message)),
+		Status:        string(d.Status),
+		Provider:      a.sanitizeLine(d.Provider),
+		TipID:         a.sanitizeLine(d.TipID),
+		Channel:       a.sanitizeLine(d.Channel),
+		Timestamp:     d.Timestamp.Local().Format("2006-01-02 15:04"),
+		Matched:       isMatched,
+		MatchedAmount: fmt.Sprintf("%.2f", matched),
+
+		Converted:       d.ConvertedCurrency != "" && !strings.EqualFold(d.ConvertedCurrency, d.Currency),
+		ConvertedAmount: fmt.Sprintf("%.2f", d.ConvertedAmount),
+		BaseCurrency:    currencyCode(a.cfg.BaseCurrency),
+
+		FormattedAmount:    a.sanitize(a.formatAmount(d.Amount, d.Currency)),
+		FormattedMatched:   a.sanitize(a.formatAmount(matched, d.Currency)),
+		FormattedConverted: a.sanitize(a.formatAmount(d.ConvertedAmount, a.cfg.BaseCurrency)),
+
+		DonorTips:           d.donorTips,
+		DonorOrdinal:        ordinal(d.donorTips),
+		RepeatDonor:         d.donorTips > 1,
+		DonorTotal:          fmt.Sprintf("%.2f", d.donorTotal),
+		FormattedDonorTotal: a.sanitize(a.formatAmount(d.donorTotal, a.cfg.BaseCurrency)),
+
+		Collapsed: d.collapsed,
+
+		Labels: a.receiptLabels(),
+	}
+}
+
+// ordinal returns n with its English ordinal suffix, e.g. "3rd".
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
+
+// parseQRTemplate parses the QR code URL template, returning nil if it is
+// empty or invalid.
+func parseQRTemplate(text string, logger *slog.Logger) *template.Template {
+	if text == "" {
+		return nil
+	}
+
+	t, err := template.New("qr").Parse(text)
+	if err != nil {
+		logger.Warn("invalid QR_URL_TEMPLATE, QR codes will be skipped", "error", err)
+		return nil
+	}
+	return t
+}
+
+// amountMark is put before the amount fields of an emphasized tip's receipt
+// data, so the lines showing the amount can be found once the template is
+// rendered. Fields are sanitized before it's added, so donor text can't
+// contain it.
+const amountMark = "\x00"
+
+// receiptText renders the receipt template for d, one printed line per line.
+// If d's amount is emphasized, the amounts are preceded by amountMark.
+func (a *Astro) receiptText(d *Donation) string {
+	data := a.newReceiptData(d)
+	username := data.Username
+	marked := data
+	if a.emphasized(d) {
+		marked.FormattedAmount = amountMark + data.FormattedAmount
+		marked.FormattedMatched = amountMark + data.FormattedMatched
+	}
+
+	var b strings.Builder
+	if a.receiptTmpl != nil {
+		marked.Username = truncateRunes(username, a.cfg.MaxUsernameLength)
+		err := a.receiptTmpl.Execute(&b, marked)
+		if err == nil {
+			return b.String()
+		}
+		a.logger.Warn("failed to render RECEIPT_TEMPLATE, using the default receipt", "error", err)
+		b.Reset()
+	}
+
+	marked.Username = a.headerUsername(d, data, username)
+	defaultReceipt.Execute(&b, marked)
+	return b.String()
+}
+
+// headerUsername cuts username to MAX_USERNAME_LENGTH and so the default
+// receipt's "Tip from X: $5.00" line fits on one line, at half the columns
+// if the amount is emphasized. At least a few runes are kept however large
+// the amount.
+func (a *Astro) headerUsername(d *Donation, data receiptData, username string) string {
+	cols := a.cfg.PrinterColumns
+	if a.emphasized(d) {
+		cols /= 2
+	}
+
+	rest := utf8.RuneCountInString(data.Labels["tip_from"]) + len(" : ") + utf8.RuneCountInString(data.FormattedAmount)
+	if data.Collapsed > 0 {
+		rest += len(fmt.Sprintf(" (x%d)", data.Collapsed))
+	}
+	fit := max(cols-rest, 4)
+	if a.cfg.MaxUsernameLength > 0 {
+		fit = min(fit, a.cfg.MaxUsernameLength)
+	}
+	return truncateRunes(username, fit)
+}
+
+// receiptQRURL renders the QR code URL for d. It returns "" if QR codes are
+// disabled or the template doesn't produce an absolute http(s) URL.
+func (a *Astro) receiptQRURL(d *Donation) string {
+	if !a.cfg.PrintQRCode || a.qrTmpl == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	if err := a.qrTmpl.Execute(&b, a.newReceiptData(d)); err != nil {
+		a.logger.Warn("failed to render QR_URL_TEMPLATE, skipping QR code", "error", err)
+		return ""
+	}
+
+	raw := strings.TrimSpace(b.String())
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		a.logger.Warn("QR_URL_TEMPLATE produced an invalid URL, skipping QR code", "url", raw)
+		return ""
+	}
+	return u.String()
+}
+
+// wrapText breaks s into lines of at most cols runes, splitting on word
+// boundaries. Words longer than cols are hard-split. Existing newlines in s
+// are kept as line breaks.
+func wrapText(s string, cols int) []string {
+	if cols <= 0 {
+		return strings.Split(s, "\n")
+	}
+
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		var line string
+		for _, word := range strings.Fields(para) {
+			for utf8.RuneCountInString(word) > cols {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				r := []rune(word)
+				lines = append(lines, string(r[:cols]))
+				word = string(r[cols:])
+			}
+
+			switch {
+			case line == "":
+				line = word
+			case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= cols:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
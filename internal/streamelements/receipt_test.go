@@ -0,0 +1,108 @@
+package streamelements
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/DaniruKun/tipfax/internal/config"
+)
+
+// TestHeaderUsername prints tips from long usernames and checks the header
+// line still fits on one line of paper.
+func TestHeaderUsername(t *testing.T) {
+	long := strings.Repeat("abcdefghij", 4) // 40 characters
+
+	tests := []struct {
+		name       string
+		username   string
+		columns    int
+		maxLength  int
+		emphasize  bool
+		wantHeader string
+	}{
+		{"short", "Alice", 32, 0, false, "Tip from Alice: 5.00 USD"},
+		{"40 characters at 32 columns", long, 32, 0, false, "Tip from abcdefghij...: 5.00 USD"},
+		{"40 characters at 48 columns", long, 48, 0, false, "Tip from abcdefghijabcdefghijabcdef...: 5.00 USD"},
+		{"MAX_USERNAME_LENGTH", long, 48, 10, false, "Tip from abcdefg...: 5.00 USD"},
+		{"multibyte", strings.Repeat("é", 40), 32, 0, false, "Tip from éééééééééé...: 5.00 USD"},
+		{"emphasized keeps a few runes", long, 32, 0, true, "Tip from a...: 5.00 USD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &recordingPrinter{}
+			a := newTestAstroPrinter(t, p, func(cfg *config.Config) {
+				cfg.PrinterColumns = tt.columns
+				cfg.MaxUsernameLength = tt.maxLength
+				cfg.CodePage = "cp850"
+				if tt.emphasize {
+					cfg.EmphasizeAmountOver = 1
+				}
+			})
+
+			d := testDonation("t1")
+			d.Username = tt.username
+			ev := NewTipEvent(TipsTopic, d, d.Timestamp)
+			if err := a.deliverDonation(ev); err != nil {
+				t.Fatalf("deliverDonation: %v", err)
+			}
+
+			receipt := strings.Join(p.Ops(), "\n")
+			if !tt.emphasize {
+				if ops := p.Ops(); ops[0] != tt.wantHeader || ops[1] != "LF" {
+					t.Errorf("header printed as %q, want %q on one line", ops[:2], tt.wantHeader)
+				}
+				if n := utf8.RuneCountInString(tt.wantHeader); n > tt.columns {
+					t.Errorf("header is %d runes, wider than %d columns", n, tt.columns)
+				}
+			} else if got := a.buildReceipt(d).Lines[0]; got != tt.wantHeader {
+				t.Errorf("header %q, want %q", got, tt.wantHeader)
+			}
+			if tt.username != "Alice" && strings.Contains(receipt, tt.username) {
+				t.Errorf("receipt has the full username: %q", receipt)
+			}
+
+			// Only the receipt is cut; the tip itself keeps the full name.
+			if got := a.RecentTips(1)[0].Donation.Username; got != tt.username {
+				t.Errorf("published username %q, want %q", got, tt.username)
+			}
+		})
+	}
+}
+
+// TestMatchWindow checks the donation match follows when a tip was sent, so
+// a tip printed after the window closes keeps its match.
+func TestMatchWindow(t *testing.T) {
+	now := time.Now()
+	start, end := now.Add(-2*time.Hour), now.Add(-time.Hour) // closed before printing
+
+	tests := []struct {
+		name string
+		sent time.Time
+		want bool
+	}{
+		{"sent before the window", start.Add(-time.Minute), false},
+		{"sent in the window", start.Add(time.Minute), true},
+		{"sent after the window", now, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &recordingPrinter{}
+			a := newTestAstroPrinter(t, p, func(cfg *config.Config) {
+				cfg.MatchMultiplier = 2
+				cfg.MatchStart, cfg.MatchEnd = start, end
+			})
+
+			d := testDonation("t1")
+			d.Timestamp = tt.sent
+			if err := a.printDonation(d); err != nil {
+				t.Fatalf("printDonation: %v", err)
+			}
+			receipt := strings.Join(p.Ops(), "\n")
+			if got := strings.Contains(receipt, "matched 10.00 USD"); got != tt.want {
+				t.Errorf("matched = %v, want %v in %q", got, tt.want, receipt)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package streamelements
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/DaniruKun/tipfax/internal/fax"
+	"github.com/securityguy/escpos"
+)
+
+// ReceiptJob is the content of one receipt, independent of how a printer
+// encodes it. Lines are not yet wrapped to the paper width.
+type ReceiptJob struct {
+	HeaderImage []byte   // raster commands printed first, nil for none
+	Lines       []string // body text, one entry per line
+	Emphasized  []int    // indexes of Lines printed bold at double size
+	QRCodeURL   string   // "" for no QR code
+	Separator   bool     // print a dashed line after the body
+	FeedLines   int      // blank lines fed before cutting
+	PartialCut  bool     // leave the receipt attached by a strip
+}
+
+// newReceiptJob returns a job printing lines, finished as configured by
+// PRINT_SEPARATOR, FEED_LINES_AFTER and CUT_MODE.
+func (a *Astro) newReceiptJob(lines []string) ReceiptJob {
+	return ReceiptJob{
+		Lines:      lines,
+		Separator:  a.cfg.PrintSeparator,
+		FeedLines:  a.cfg.FeedLinesAfter,
+		PartialCut: a.cfg.CutMode == "partial",
+	}
+}
+
+// buildReceipt collects everything printed for d.
+func (a *Astro) buildReceipt(d *Donation) ReceiptJob {
+	if a.CompactReceipts() {
+		return a.buildCompactReceipt(d)
+	}
+
+	lines := strings.Split(strings.TrimRight(a.receiptText(d), "\n"), "\n")
+	if a.cfg.PrintChannel && d.Channel != "" {
+		lines = append([]string{"[" + a.sanitizeLine(d.Channel) + "]"}, lines...)
+	}
+	if footer := a.footer.pick(); footer != "" {
+		lines = append(lines, "", a.sanitize(footer))
+	}
+	job := a.newReceiptJob(lines)
+	job.HeaderImage = a.headerImage
+	job.QRCodeURL = a.receiptQRURL(d)
+	job.Emphasized = emphasizedLines(lines)
+	return job
+}
+
+// emphasizedLines returns the indexes of the lines receiptText marked with
+// amountMark, removing the marks.
+func emphasizedLines(lines []string) []int {
+	var idx []int
+	for i, line := range lines {
+		if strings.Contains(line, amountMark) {
+			lines[i] = strings.ReplaceAll(line, amountMark, "")
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// emphasized reports whether d is worth more than EmphasizeAmountOver in
+// BaseCurrency.
+func (a *Astro) emphasized(d *Donation) bool {
+	if a.cfg.EmphasizeAmountOver <= 0 {
+		return false
+	}
+	amount, ok := a.baseAmount(d)
+	if !ok {
+		amount = d.Amount
+	}
+	return amount > a.cfg.EmphasizeAmountOver
+}
+
+// receiptRenderer turns receipt jobs into printer commands for paper that
+// fits columns characters per line.
+type receiptRenderer struct {
+	columns int
+	qrSize  uint8
+}
+
+func (a *Astro) renderer() receiptRenderer {
+	return receiptRenderer{columns: a.cfg.PrinterColumns, qrSize: a.cfg.QRCodeSize}
+}
+
+// render prints job on p. Parts p can't print, such as images on the
+// console, are skipped. It must only be called from p's station job.
+func (r receiptRenderer) render(p fax.Printer, job ReceiptJob) error {
+	if job.HeaderImage != nil {
+		if rp, ok := p.(fax.RasterPrinter); ok {
+			if _, err := rp.WriteRaw(job.HeaderImage); err != nil {
+				return fmt.Errorf("print header image: %w", err)
+			}
+		}
+	}
+
+	for i, line := range job.Lines {
+		var err error
+		if slices.Contains(job.Emphasized, i) {
+			err = r.printEmphasized(p, line)
+		} else {
+			err = r.printWrapped(p, line, r.columns)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if job.QRCodeURL != "" {
+		if qr, ok := p.(fax.QRCodePrinter); ok {
+			if _, err := qr.QRCode(job.QRCodeURL, true, r.qrSize, escpos.QRCodeErrorCorrectionLevelM); err != nil {
+				return fmt.Errorf("print QR code: %w", err)
+			}
+			if _, err := p.LineFeed(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return r.finish(p, job)
+}
+
+// finish prints the optional separator, feeds blank lines so the cutter
+// clears the last line of text, and cuts the paper.
+func (r receiptRenderer) finish(p fax.Printer, job ReceiptJob) error {
+	if job.Separator {
+		if err := printLine(p, strings.Repeat("-", max(r.columns, 1))); err != nil {
+			return err
+		}
+	}
+	for range job.FeedLines {
+		if _, err := p.LineFeed(); err != nil {
+			return err
+		}
+	}
+
+	if job.PartialCut {
+		if pc, ok := p.(fax.PartialCutter); ok {
+			return pc.PrintAndPartialCut()
+		}
+	}
+	return p.PrintAndCut()
+}
+
+func (r receiptRenderer) printWrapped(p fax.Printer, line string, cols int) error {
+	for _, wrapped := range wrapText(line, cols) {
+		if err := printLine(p, wrapped); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printEmphasized prints line bold at double size, wrapped to half the
+// columns, and then switches back to normal text even if printing failed.
+// Printers that can't emphasize print it normally.
+func (r receiptRenderer) printEmphasized(p fax.Printer, line string) error {
+	e, ok := p.(fax.Emphasizer)
+	if !ok {
+		return r.printWrapped(p, line, r.columns)
+	}
+
+	e.Emphasize(true)
+	defer e.Emphasize(false)
+	return r.printWrapped(p, line, r.columns/2)
+}
+
+func printLine(p fax.Printer, line string) error {
+	if _, err := p.Write(line); err != nil {
+		return err
+	}
+	_, err := p.LineFeed()
+	return err
+}
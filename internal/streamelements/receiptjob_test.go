@@ -0,0 +1,103 @@
+package streamelements
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/DaniruKun/tipfax/internal/config"
+)
+
+func TestEmphasizedAmount(t *testing.T) {
+	tests := []struct {
+		name   string
+		over   float64
+		amount float64
+		want   []string
+	}{
+		{"disabled", 0, 500, []string{"Tip from Bob: 500.00 USD", "LF", "Status: completed", "LF", "CUT"}},
+		{"below", 100, 5, []string{"Tip from Bob: 5.00 USD", "LF", "Status: completed", "LF", "CUT"}},
+		{"at threshold", 100, 100, []string{"Tip from Bob: 100.00 USD", "LF", "Status: completed", "LF", "CUT"}},
+		{"above", 100, 500, []string{
+			"EMPHASIZE ON", "Tip from Bob:", "LF", "500.00 USD", "LF", "EMPHASIZE OFF",
+			"Status: completed", "LF", "CUT",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &recordingPrinter{}
+			a := newTestAstroPrinter(t, p, func(cfg *config.Config) {
+				cfg.EmphasizeAmountOver = tt.over
+				cfg.FeedLinesAfter = 0
+			})
+
+			d := testDonation("t1")
+			d.Username, d.Amount = "Bob", tt.amount
+			if err := a.printDonation(d); err != nil {
+				t.Fatalf("printDonation: %v", err)
+			}
+			if got := p.Ops(); !slices.Equal(got, tt.want) {
+				t.Errorf("printed\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEmphasizedLines checks only the lines the template prints the amount
+// on are emphasized, not donor text that happens to contain it.
+func TestEmphasizedLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		message  string
+		want     []int
+	}{
+		{"default", "", "15.00 USD next time", []int{0}},
+		{"custom", "{{.Message}}\\n{{.Username}}\\n{{.FormattedAmount}}", "5.00 USD", []int{2}},
+		{"custom without amount", "{{.Username}}: {{.Message}}", "5.00 USD", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAstro(t, func(cfg *config.Config) {
+				cfg.EmphasizeAmountOver = 1
+				cfg.ReceiptTemplate = tt.template
+			})
+
+			d := testDonation("t1")
+			d.Username, d.Message = "5.00 USD", tt.message
+			job := a.buildReceipt(d)
+			if !slices.Equal(job.Emphasized, tt.want) {
+				t.Errorf("emphasized lines %v of %q, want %v", job.Emphasized, job.Lines, tt.want)
+			}
+			for _, line := range job.Lines {
+				if strings.Contains(line, amountMark) {
+					t.Errorf("line %q still has the amount mark", line)
+				}
+			}
+		})
+	}
+}
+
+// failingPrinter fails every write.
+type failingPrinter struct{ recordingPrinter }
+
+func (p *failingPrinter) Write(data string) (int, error) {
+	p.record(data)
+	return 0, errors.New("paper jam")
+}
+
+// TestEmphasisResetOnError checks a failed emphasized line still switches
+// emphasis off, so the next receipt doesn't print at double size.
+func TestEmphasisResetOnError(t *testing.T) {
+	p := &failingPrinter{}
+	err := receiptRenderer{columns: 32}.render(p, ReceiptJob{Lines: []string{"500.00 USD"}, Emphasized: []int{0}})
+	if err == nil {
+		t.Fatal("render succeeded on a failing printer")
+	}
+
+	ops := p.Ops()
+	if len(ops) == 0 || ops[len(ops)-1] != "EMPHASIZE OFF" {
+		t.Errorf("emphasis not switched off after the failure: %q", strings.Join(ops, ", "))
+	}
+}
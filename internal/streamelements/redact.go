@@ -0,0 +1,34 @@
+package streamelements
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// maskToken returns a preview of token that is safe to log: its first and
+// last few characters, or nothing at all for short tokens.
+func maskToken(token string) string {
+	if len(token) <= 20 {
+		return "[redacted]"
+	}
+	return token[:10] + "..." + token[len(token)-10:]
+}
+
+// redact renders v for logging with every occurrence of the JWT masked. Use it
+// for any dump of message data, which may echo the token back.
+func (a *Astro) redact(v any) string {
+	var s string
+	if b, err := json.Marshal(v); err == nil {
+		s = string(b)
+	} else {
+		s = fmt.Sprintf("%+v", v)
+	}
+
+	for _, token := range []string{a.cfg.SeJWTToken, a.cfg.SeModerationJWTToken, a.jwt()} {
+		if token != "" {
+			s = strings.ReplaceAll(s, token, maskToken(token))
+		}
+	}
+	return s
+}
@@ -0,0 +1,102 @@
+package streamelements
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DaniruKun/tipfax/internal/config"
+	"github.com/DaniruKun/tipfax/internal/fax"
+)
+
+// logBuffer collects log output written from several goroutines.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestLogsRedactToken(t *testing.T) {
+	token := testJWT()
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, ctx context.Context, a *Astro)
+	}{
+		{"subscribe", func(t *testing.T, ctx context.Context, a *Astro) {
+			if err := a.SubscribeTips(ctx); err != nil {
+				t.Fatalf("SubscribeTips: %v", err)
+			}
+		}},
+		{"unsubscribe", func(t *testing.T, ctx context.Context, a *Astro) {
+			if err := a.SubscribeTips(ctx); err != nil {
+				t.Fatalf("SubscribeTips: %v", err)
+			}
+			a.mu.Lock()
+			subs := a.subs
+			a.mu.Unlock()
+			if err := a.unsubscribeAll(ctx, subs); err != nil {
+				t.Fatalf("unsubscribeAll: %v", err)
+			}
+		}},
+		{"token echoed in a message", func(t *testing.T, ctx context.Context, a *Astro) {
+			data, _ := json.Marshal(map[string]any{"message": "invalid token", "token": token})
+			a.handleMessage(Message{Type: "response", Nonce: "n1", Data: data})
+			a.handleMessage(Message{Type: "surprise", Data: data})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockServer()
+			defer mock.Close()
+
+			cfg, err := config.Defaults()
+			if err != nil {
+				t.Fatalf("config.Defaults: %v", err)
+			}
+			cfg.AstroURL = mock.URL()
+			cfg.SeJWTToken = token
+			cfg.DebugRawMessages = true
+			var logs logBuffer
+			logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			a := NewAstro(cfg, fax.NewConsolePrinter(io.Discard), logger)
+
+			if err := a.Connect(); err != nil {
+				t.Fatalf("Connect: %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			listened := make(chan error, 1)
+			go func() { listened <- a.Listen(ctx) }()
+
+			tt.run(t, ctx, a)
+			cancel()
+			<-listened
+
+			// The masked form shows the token-bearing lines were logged at all.
+			out := logs.String()
+			if !strings.Contains(out, maskToken(token)) {
+				t.Fatalf("masked token not logged:\n%s", out)
+			}
+			if strings.Contains(out, token) {
+				t.Errorf("raw token found in log output:\n%s", out)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package streamelements
+
+import "strings"
+
+var (
+	responseSuccessKeywords = []string{"success", "subscribed"}
+	responseErrorKeywords   = []string{"error", "failed", "invalid", "unauthorized", "forbidden", "not found"}
+)
+
+// classifyResponse decides whether the data of a response message reports
+// success, and returns its message, if any. Astro doesn't flag failures
+// consistently, so a response with a message fails if it has a code field or
+// type "error", or if the message contains an error keyword and no success
+// keyword. Responses without a message are treated as successful.
+func classifyResponse(data map[string]any) (ok bool, message string) {
+	message, hasMessage := data["message"].(string)
+	if !hasMessage {
+		return true, ""
+	}
+
+	lower := strings.ToLower(message)
+	isError := false
+	if !containsAny(lower, responseSuccessKeywords) {
+		isError = containsAny(lower, responseErrorKeywords)
+	}
+	if _, hasCode := data["code"]; hasCode {
+		isError = true
+	}
+	if t, _ := data["type"].(string); t == "error" {
+		isError = true
+	}
+
+	return !isError, message
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAuthError reports whether an error response message points at the token.
+func isAuthError(message string) bool {
+	return containsAny(strings.ToLower(message), []string{"unauthorized", "forbidden", "token", "auth"})
+}
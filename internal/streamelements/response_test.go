@@ -0,0 +1,95 @@
+package streamelements
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestClassifyResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        map[string]any
+		wantOK      bool
+		wantMessage string
+	}{
+		{"success", map[string]any{"message": "successfully subscribed to topic"}, true, "successfully subscribed to topic"},
+		{"subscribed", map[string]any{"message": "Subscribed"}, true, "Subscribed"},
+		{"neutral message", map[string]any{"message": "ok"}, true, "ok"},
+		{"error keyword", map[string]any{"message": "Invalid token"}, false, "Invalid token"},
+		{"not found", map[string]any{"message": "topic not found"}, false, "topic not found"},
+		{"success outweighs error keyword", map[string]any{"message": "subscribed, invalid room ignored"}, true, "subscribed, invalid room ignored"},
+		{"code field", map[string]any{"message": "subscribed", "code": "E401"}, false, "subscribed"},
+		{"type error", map[string]any{"message": "subscribed", "type": "error"}, false, "subscribed"},
+		{"type other", map[string]any{"message": "subscribed", "type": "response"}, true, "subscribed"},
+		{"no message", map[string]any{"topic": "channel.tips"}, true, ""},
+		{"no message with code", map[string]any{"code": "E401"}, true, ""},
+		{"message not a string", map[string]any{"message": 42}, true, ""},
+		{"nil data", nil, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, message := classifyResponse(tt.data)
+			if ok != tt.wantOK || message != tt.wantMessage {
+				t.Errorf("classifyResponse(%v) = %v, %q, want %v, %q", tt.data, ok, message, tt.wantOK, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestIsAuthError(t *testing.T) {
+	tests := []struct {
+		message string
+		want    bool
+	}{
+		{"Unauthorized", true},
+		{"forbidden", true},
+		{"invalid token", true},
+		{"authentication failed", true},
+		{"topic not found", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isAuthError(tt.message); got != tt.want {
+			t.Errorf("isAuthError(%q) = %v, want %v", tt.message, got, tt.want)
+		}
+	}
+}
+
+// TestHandleResponse checks which responses mark Astro subscribed: only
+// successful answers to our own subscribe requests.
+func TestHandleResponse(t *testing.T) {
+	tests := []struct {
+		name           string
+		kind           string // request sent, if any
+		nonce          string // nonce answered; "" answers the request sent
+		data           string
+		wantSubscribed bool
+	}{
+		{"ok", "subscribe", "", `{"message":"successfully subscribed to topic"}`, true},
+		{"error", "subscribe", "", `{"message":"invalid token","code":"E401"}`, false},
+		{"unknown nonce", "subscribe", "someone-else", `{"message":"successfully subscribed to topic"}`, false},
+		{"unsubscribe", "unsubscribe", "", `{"message":"success"}`, false},
+		{"malformed", "subscribe", "", `"subscribed"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAstro(t, nil)
+
+			nonce, err := a.newRequest(tt.kind, TipsTopic)
+			if err != nil {
+				t.Fatalf("newRequest: %v", err)
+			}
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+			a.handleMessage(Message{Type: "response", Nonce: nonce, Data: json.RawMessage(tt.data)})
+
+			a.mu.Lock()
+			subscribed := a.subscribed
+			a.mu.Unlock()
+			if subscribed != tt.wantSubscribed {
+				t.Errorf("subscribed = %v, want %v", subscribed, tt.wantSubscribed)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package streamelements
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/DaniruKun/tipfax/internal/fax"
+)
+
+// Sanitize modes for text sent to the printer, selected by config.
+const (
+	SanitizeStrip         = "strip"         // drop characters outside ASCII
+	SanitizeReplace       = "replace"       // replace them with '?'
+	SanitizeTransliterate = "transliterate" // map accented Latin to ASCII, '?' otherwise
+)
+
+// sanitizeForPrinter makes s safe for the printer's code page cp, or for
+// ASCII if cp is nil. Characters cp has are kept; others are handled per
+// mode. Emoji, including multi-rune sequences joined with ZWJ or modified by
+// variation selectors and skin tones, are always removed, as are control
+// characters other than newline so a tip can't send ESC/POS commands.
+func sanitizeForPrinter(s, mode string, cp *fax.CodePage) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for _, r := range s {
+		switch {
+		case r == utf8.RuneError:
+			continue
+		case (r < 0x20 && r != '\n') || r == 0x7F:
+			continue
+		case r < utf8.RuneSelf:
+			b.WriteRune(r)
+		case isEmojiRune(r):
+			continue
+		case cp != nil && cp.Has(r):
+			b.WriteRune(r)
+		case mode == SanitizeStrip:
+			continue
+		case mode == SanitizeTransliterate:
+			if t, ok := transliterations[r]; ok {
+				b.WriteString(t)
+			} else {
+				b.WriteByte('?')
+			}
+		default:
+			b.WriteByte('?')
+		}
+	}
+
+	return b.String()
+}
+
+// isEmojiRune reports whether r is an emoji or a rune that only appears as
+// part of an emoji sequence.
+func isEmojiRune(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // pictographs, emoticons, flags, skin tones
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // misc symbols and dingbats
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF: // arrows and stars used as emoji
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F: // variation selectors
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // tag sequences
+		return true
+	case r == 0x200D, r == 0x20E3: // zero width joiner, keycap
+		return true
+	}
+	return false
+}
+
+var transliterations = map[rune]string{
+	'À': "A", 'Á': "A", 'Â': "A", 'Ã': "A", 'Ä': "A", 'Å': "A", 'Æ': "AE",
+	'Ç': "C", 'È': "E", 'É': "E", 'Ê': "E", 'Ë': "E",
+	'Ì': "I", 'Í': "I", 'Î': "I", 'Ï': "I", 'Ð': "D", 'Ñ': "N",
+	'Ò': "O", 'Ó': "O", 'Ô': "O", 'Õ': "O", 'Ö': "O", 'Ø': "O",
+	'Ù': "U", 'Ú': "U", 'Û': "U", 'Ü': "U", 'Ý': "Y", 'Þ': "TH", 'ß': "ss",
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'æ': "ae",
+	'ç': "c", 'è': "e", 'é': "e", 'ê': "e", 'ë': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ð': "d", 'ñ': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ý': "y", 'þ': "th", 'ÿ': "y",
+	'Ā': "A", 'ā': "a", 'Ą': "A", 'ą': "a", 'Ć': "C", 'ć': "c", 'Č': "C", 'č': "c",
+	'Đ': "D", 'đ': "d", 'Ď': "D", 'ď': "d", 'Ē': "E", 'ē': "e", 'Ę': "E", 'ę': "e",
+	'Ě': "E", 'ě': "e", 'Ğ': "G", 'ğ': "g", 'Ī': "I", 'ī': "i", 'İ': "I", 'ı': "i",
+	'Ł': "L", 'ł': "l", 'Ń': "N", 'ń': "n", 'Ň': "N", 'ň': "n", 'Ō': "O", 'ō': "o",
+	'Ő': "O", 'ő': "o", 'Œ': "OE", 'œ': "oe", 'Ř': "R", 'ř': "r", 'Ś': "S", 'ś': "s",
+	'Ş': "S", 'ş': "s", 'Š': "S", 'š': "s", 'Ť': "T", 'ť': "t", 'Ū': "U", 'ū': "u",
+	'Ů': "U", 'ů': "u", 'Ű': "U", 'ű': "u", 'Ź': "Z", 'ź': "z", 'Ż': "Z", 'ż': "z",
+	'Ž': "Z", 'ž': "z",
+	'‘': "'", '’': "'", '‚': "'", '“': "\"", '”': "\"", '„': "\"",
+	'–': "-", '—': "-", '…': "...", '•': "*", '€': "EUR", '£': "GBP", '¥': "JPY",
+	'\u00a0': " ", // no-break space
+}
+
+// sanitize is sanitizeForPrinter with SANITIZE_MODE and CODE_PAGE.
+func (a *Astro) sanitize(s string) string {
+	return sanitizeForPrinter(s, a.cfg.SanitizeMode, a.codePage)
+}
+
+// sanitizeLine is sanitize for a field printed within one line, such as the
+// username: newlines become spaces so a donor can't break the layout.
+func (a *Astro) sanitizeLine(s string) string {
+	return strings.ReplaceAll(a.sanitize(s), "\n", " ")
+}
+
+// sanitizeMessage is sanitize for the tip message. Blank lines are dropped
+// and at most MAX_MESSAGE_LINES lines kept, then the message is cut to
+// MAX_MESSAGE_LENGTH runes, so a message can't feed out a length of empty
+// paper.
+func (a *Astro) sanitizeMessage(s string) string {
+	var lines []string
+	for _, line := range strings.Split(a.sanitize(s), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if n := a.cfg.MaxMessageLines; n > 0 && len(lines) > n {
+		lines = lines[:n]
+		lines[n-1] += "..."
+	}
+	return truncateRunes(strings.Join(lines, "\n"), a.cfg.MaxMessageLength)
+}
+
+// truncateRunes shortens s to at most n runes, ending it with "..." if it was
+// cut. n <= 0 means no limit.
+func truncateRunes(s string, n int) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	if n <= 3 {
+		return string(r[:n])
+	}
+	return string(r[:n-3]) + "..."
+}
@@ -0,0 +1,189 @@
+package streamelements
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/DaniruKun/tipfax/internal/config"
+	"github.com/DaniruKun/tipfax/internal/fax"
+)
+
+func TestSanitizeForPrinter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		mode string
+		cp   *fax.CodePage
+		want string
+	}{
+		{"plain", "thanks!", SanitizeReplace, nil, "thanks!"},
+		{"ESC @ reset", "hi\x1b@there", SanitizeReplace, nil, "hi@there"},
+		{"ESC sequences", "\x1bE\x01bold\x1d!\x11big", SanitizeReplace, nil, "Ebold!big"},
+		{"NUL and bell", "a\x00b\x07c", SanitizeReplace, nil, "abc"},
+		{"tab and CR", "a\tb\r\nc", SanitizeReplace, nil, "ab\nc"},
+		{"DEL", "a\x7fb", SanitizeReplace, nil, "ab"},
+		{"newline kept", "line1\nline2", SanitizeReplace, nil, "line1\nline2"},
+		{"invalid UTF-8", "a\xffb", SanitizeReplace, nil, "ab"},
+		{"emoji", "gg 👍🏽 ❤️ 👨‍👩‍👧", SanitizeReplace, nil, "gg   "},
+		{"replace", "café", SanitizeReplace, nil, "caf?"},
+		{"strip", "café", SanitizeStrip, nil, "caf"},
+		{"transliterate", "café – “ok”", SanitizeTransliterate, nil, "cafe - \"ok\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeForPrinter(tt.in, tt.mode, tt.cp); got != tt.want {
+				t.Errorf("sanitizeForPrinter(%q, %q) = %q, want %q", tt.in, tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 5, "hello"},
+		{"hello world", 8, "hello..."},
+		{"héllo wörld", 8, "héllo..."},
+		{"ありがとうございます", 6, "ありが..."},
+		{"hello", 2, "he"},
+		{"hello", 0, "hello"},
+		{"hello", -1, "hello"},
+	}
+	for _, tt := range tests {
+		if got := truncateRunes(tt.in, tt.n); got != tt.want {
+			t.Errorf("truncateRunes(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+}
+
+// TestReceiptControlBytes prints tips carrying ESC/POS commands and checks
+// none of them reach the printer.
+func TestReceiptControlBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		message  string
+		want     string // text expected on the receipt
+	}{
+		{"ESC @ in message", "Alice", "hi\x1b@there", "hi@there"},
+		{"ESC @ in username", "Ev\x1b@il", "hi", "Ev@il"},
+		{"cut command", "Alice", "snip\x1dV\x00snap", "snipVsnap"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &recordingPrinter{}
+			a := newTestAstroPrinter(t, p, nil)
+
+			d := testDonation("t1")
+			d.Username, d.Message = tt.username, tt.message
+			if err := a.printDonation(d); err != nil {
+				t.Fatalf("printDonation: %v", err)
+			}
+
+			receipt := strings.Join(p.Ops(), "\n")
+			if strings.ContainsFunc(receipt, func(r rune) bool { return r < 0x20 && r != '\n' }) {
+				t.Errorf("control bytes reached the printer: %q", receipt)
+			}
+			if !strings.Contains(receipt, tt.want) {
+				t.Errorf("receipt %q doesn't contain %q", receipt, tt.want)
+			}
+		})
+	}
+}
+
+func TestReceiptMessageLength(t *testing.T) {
+	tests := []struct {
+		name    string
+		max     int
+		message string
+		want    string
+	}{
+		{"short", 20, "thanks", "thanks"},
+		{"long", 10, "aaaaaaaaaaaaaaaaaaaa", "aaaaaaa..."},
+		{"multibyte", 5, "éééééééééé", "éé..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &recordingPrinter{}
+			a := newTestAstroPrinter(t, p, func(cfg *config.Config) {
+				cfg.MaxMessageLength = tt.max
+				cfg.CodePage = "cp850"
+			})
+
+			d := testDonation("t1")
+			d.Message = tt.message
+			if err := a.printDonation(d); err != nil {
+				t.Fatalf("printDonation: %v", err)
+			}
+
+			receipt := strings.Join(p.Ops(), "")
+			if !strings.Contains(receipt, tt.want) {
+				t.Errorf("receipt %q doesn't contain %q", receipt, tt.want)
+			}
+			if tt.want != tt.message && strings.Contains(receipt, tt.message) {
+				t.Errorf("receipt has the whole message %q", tt.message)
+			}
+		})
+	}
+}
+
+// TestReceiptNewlines checks newlines typed by a donor don't add lines to the
+// receipt beyond the message's own, capped to MAX_MESSAGE_LINES.
+func TestReceiptNewlines(t *testing.T) {
+	count := func(ops []string, op string) int {
+		n := 0
+		for _, o := range ops {
+			if o == op {
+				n++
+			}
+		}
+		return n
+	}
+	printOps := func(t *testing.T, d *Donation) []string {
+		t.Helper()
+		p := &recordingPrinter{}
+		a := newTestAstroPrinter(t, p, func(cfg *config.Config) { cfg.MaxMessageLines = 3 })
+		if err := a.printDonation(d); err != nil {
+			t.Fatalf("printDonation: %v", err)
+		}
+		return p.Ops()
+	}
+	base := count(printOps(t, testDonation("t1")), "LF")
+
+	t.Run("username", func(t *testing.T) {
+		d := testDonation("t1")
+		d.Username = "Ev\n\nil"
+		ops := printOps(t, d)
+		if n := count(ops, "LF"); n != base {
+			t.Errorf("got %d line feeds, want %d", n, base)
+		}
+		if !slices.ContainsFunc(ops, func(op string) bool { return strings.Contains(op, "Ev il") }) {
+			t.Errorf("receipt %q doesn't have the username on one line", ops)
+		}
+	})
+
+	t.Run("blank lines", func(t *testing.T) {
+		d := testDonation("t1")
+		d.Message = strings.Repeat("\n", 200)
+		if n := count(printOps(t, d), "LF"); n != base {
+			t.Errorf("got %d line feeds, want %d", n, base)
+		}
+	})
+
+	t.Run("line cap", func(t *testing.T) {
+		d := testDonation("t1")
+		d.Message = strings.Repeat("spam\n\n", 20)
+		ops := printOps(t, d)
+		if n := count(ops, "LF"); n != base+3 {
+			t.Errorf("got %d line feeds, want %d", n, base+3)
+		}
+		if !slices.Contains(ops, "spam...") {
+			t.Errorf("receipt %q doesn't end the message with spam...", ops)
+		}
+	})
+}
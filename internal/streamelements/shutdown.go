@@ -0,0 +1,50 @@
+package streamelements
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/DaniruKun/tipfax/internal/fax"
+)
+
+// Shutdown stops everything in order: it unsubscribes from every active
+// topic, prints tips held by the anti-spam guard and the queued tips until ctx
+// is done, closes the printers, closes the connection to Astro, and closes
+// the tip log and database. Tips left in the queue are logged. Errors from
+// every step are joined. Cancel the context passed to ListenWithReconnect
+// first, so it doesn't reconnect.
+func (a *Astro) Shutdown(ctx context.Context) error {
+	var errs []error
+
+	a.mu.Lock()
+	connected := a.connected
+	subs := slices.Clone(a.subs)
+	a.mu.Unlock()
+
+	if connected {
+		errs = append(errs, a.unsubscribeAll(ctx, subs))
+	}
+
+	a.flushPrintQueue(ctx)
+
+	for _, name := range a.stationOrder {
+		st := a.stations[name]
+		c, ok := st.printer.(io.Closer)
+		if !ok {
+			continue
+		}
+		if err := st.do(func(fax.Printer) error { return c.Close() }); err != nil {
+			errs = append(errs, fmt.Errorf("close printer %s: %w", name, err))
+		}
+	}
+
+	if connected {
+		errs = append(errs, a.Disconnect())
+	}
+
+	errs = append(errs, a.Close())
+	return errors.Join(errs...)
+}
@@ -0,0 +1,128 @@
+package streamelements
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// spamGuard collapses floods of identical tips, same donor, message and
+// currency, arriving less than window apart. The first max tips of a run are
+// printed as usual; later ones are held and handed to flush as one batch once
+// the run has been quiet for window.
+type spamGuard struct {
+	window time.Duration
+	max    int
+	flush  func(held []*Donation)
+
+	mu   sync.Mutex
+	runs map[string]*spamRun
+}
+
+// spamRun is one donor's current streak of identical tips.
+type spamRun struct {
+	count int         // tips in the run, printed or held
+	held  []*Donation // tips past max, not printed yet
+	timer *time.Timer // ends the run after window without another tip
+}
+
+// newSpamGuard returns nil if window is 0, meaning anti-spam is off.
+func newSpamGuard(window time.Duration, max int, flush func([]*Donation)) *spamGuard {
+	if window <= 0 {
+		return nil
+	}
+	return &spamGuard{window: window, max: max, flush: flush, runs: make(map[string]*spamRun)}
+}
+
+// spamKey identifies identical tips from one donor. Anonymous tips can't be
+// told apart by donor, so they get "" and are never collapsed.
+func spamKey(d *Donation) string {
+	donor := donorKey(d.Username)
+	if donor == "" {
+		return ""
+	}
+	return donor + "\x00" + currencyCode(d.Currency) + "\x00" + strings.ToLower(strings.TrimSpace(d.Message))
+}
+
+// hold reports whether d repeats a run of more than max identical tips, in
+// which case it is held for the collapsed receipt instead of printed.
+func (g *spamGuard) hold(d *Donation) bool {
+	key := spamKey(d)
+	if key == "" {
+		return false
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	r, ok := g.runs[key]
+	if ok {
+		r.timer.Reset(g.window)
+	} else {
+		r = &spamRun{}
+		r.timer = time.AfterFunc(g.window, func() { g.expire(key, r) })
+		g.runs[key] = r
+	}
+
+	r.count++
+	if r.count <= g.max {
+		return false
+	}
+	r.held = append(r.held, d)
+	return true
+}
+
+// expire ends the run r and flushes its held tips.
+func (g *spamGuard) expire(key string, r *spamRun) {
+	g.mu.Lock()
+	if g.runs[key] != r {
+		g.mu.Unlock()
+		return
+	}
+	delete(g.runs, key)
+	held := r.held
+	g.mu.Unlock()
+
+	if len(held) > 0 {
+		g.flush(held)
+	}
+}
+
+// flushAll ends every run right away, e.g. at shutdown.
+func (g *spamGuard) flushAll() {
+	g.mu.Lock()
+	runs := g.runs
+	g.runs = make(map[string]*spamRun)
+	g.mu.Unlock()
+
+	for _, r := range runs {
+		r.timer.Stop()
+		if len(r.held) > 0 {
+			g.flush(r.held)
+		}
+	}
+}
+
+// flushSpam prints one receipt for tips held by the spam guard, with their
+// total amount and count, if SPAM_PRINT_SUMMARY is set.
+func (a *Astro) flushSpam(held []*Donation) {
+	last := held[len(held)-1]
+	if !a.cfg.SpamPrintSummary {
+		a.logger.Info("not printing collapsed repeated tips", "username", last.Username, "count", len(held))
+		return
+	}
+
+	collapsed := *last
+	collapsed.TipID = "" // stands for several tips
+	collapsed.Amount = 0
+	for _, d := range held {
+		collapsed.Amount += d.Amount
+	}
+	collapsed.ConvertedAmount, collapsed.ConvertedCurrency = 0, ""
+	collapsed.collapsed = len(held)
+	a.convertDonation(&collapsed)
+
+	a.logger.Info("printing collapsed repeated tips", "username", collapsed.Username, "count", len(held),
+		"amount", collapsed.Amount, "currency", collapsed.Currency)
+	a.printDonation(&collapsed)
+}
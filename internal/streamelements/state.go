@@ -0,0 +1,71 @@
+package streamelements
+
+import (
+	"slices"
+	"time"
+)
+
+// State is a stage of the connection to Astro, see SubscribeStates.
+type State string
+
+const (
+	StateConnecting   State = "connecting"
+	StateConnected    State = "connected"
+	StateSubscribed   State = "subscribed" // Astro acknowledged a subscription on this connection
+	StateReconnecting State = "reconnecting"
+	StateDisconnected State = "disconnected"
+)
+
+// StateChange is a transition to State. Reason says why for failures, e.g.
+// the error that dropped the connection, and is empty otherwise.
+type StateChange struct {
+	State  State     `json:"state"`
+	Reason string    `json:"reason,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// stateBuffer is how many state changes each subscriber buffers when it is
+// slow.
+const stateBuffer = 16
+
+// SubscribeStates returns a channel that receives every connection state
+// change, e.g. to show a connection indicator when embedding Astro. Changes
+// are sent without blocking: a subscriber more than stateBuffer changes
+// behind misses new ones, so it can't stall the connection. Call cancel once
+// done; the channel is then closed.
+func (a *Astro) SubscribeStates() (states <-chan StateChange, cancel func()) {
+	ch := make(chan StateChange, stateBuffer)
+
+	a.mu.Lock()
+	a.stateSubs = append(a.stateSubs, ch)
+	a.mu.Unlock()
+
+	return ch, func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if i := slices.Index(a.stateSubs, ch); i >= 0 {
+			a.stateSubs = slices.Delete(a.stateSubs, i, i+1)
+			close(ch)
+		}
+	}
+}
+
+// setState records a transition to s and sends it to every state
+// subscriber. Repeating the current state with the same reason is ignored.
+func (a *Astro) setState(s State, reason string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.state.State == s && a.state.Reason == reason {
+		return
+	}
+	a.state = StateChange{State: s, Reason: reason, At: time.Now()}
+
+	for _, ch := range a.stateSubs {
+		select {
+		case ch <- a.state:
+		default:
+			a.logger.Warn("state consumer is falling behind, dropping state change", "state", s)
+		}
+	}
+}
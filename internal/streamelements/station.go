@@ -0,0 +1,131 @@
+package streamelements
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/DaniruKun/tipfax/internal/config"
+	"github.com/DaniruKun/tipfax/internal/fax"
+)
+
+// station is a named printer with its own offline queue. All access to the
+// printer goes through do, so the device is written to by one goroutine no
+// matter how many tips, summaries and queue drains are in flight.
+type station struct {
+	name    string
+	printer fax.Printer
+	jobs    chan printJob
+	queue   *printQueue
+	wake    chan struct{} // nudges the queue worker
+	limiter *tokenBucket  // nil if printing isn't rate limited
+
+	mu          sync.Mutex
+	faultReason string // set while the printer reports an error
+}
+
+// printJob is one unit of work for a station's printer goroutine.
+type printJob struct {
+	fn   func(fax.Printer) error
+	done chan error
+}
+
+// run executes print jobs one at a time. It never returns.
+func (st *station) run() {
+	for job := range st.jobs {
+		job.done <- job.fn(st.printer)
+	}
+}
+
+// do runs fn on the station's printer goroutine and waits for its result.
+func (st *station) do(fn func(fax.Printer) error) error {
+	done := make(chan error, 1)
+	st.jobs <- printJob{fn: fn, done: done}
+	return <-done
+}
+
+// enqueue adds d to the station's print queue and wakes its worker.
+func (st *station) enqueue(d *Donation) {
+	st.queue.Push(d)
+	st.wakeQueue()
+}
+
+// wakeQueue makes the queue worker try to print right away.
+func (st *station) wakeQueue() {
+	select {
+	case st.wake <- struct{}{}:
+	default:
+	}
+}
+
+// AddPrinter registers p under name so print rules can route tips to it. The
+// printer passed to NewAstro is registered as cfg.DefaultPrinter.
+func (a *Astro) AddPrinter(name string, p fax.Printer) {
+	st := &station{
+		name:    name,
+		printer: p,
+		jobs:    make(chan printJob),
+		queue:   newPrintQueue(a.cfg.PrintQueueSize, a.logger),
+		wake:    make(chan struct{}, 1),
+		limiter: newTokenBucket(a.cfg.PrintRatePerMinute, a.cfg.PrintBurst),
+	}
+
+	a.stations[name] = st
+	a.stationOrder = append(a.stationOrder, name)
+	go st.run()
+	go a.runPrintQueue(st)
+	go a.runStatusPoll(st)
+}
+
+// queuedTips returns the number of tips waiting on all stations.
+func (a *Astro) queuedTips() int {
+	var n int
+	for _, st := range a.stations {
+		n += st.queue.Len()
+	}
+	return n
+}
+
+// routeDonation returns the stations that should print d: every station named
+// by a matching print rule, or the default station if no rule matches.
+// Rules naming a printer that isn't available fall back to the default.
+func (a *Astro) routeDonation(d *Donation) []*station {
+	var targets []*station
+	added := make(map[string]bool)
+
+	for _, rule := range a.cfg.PrintRules {
+		if !a.ruleMatches(rule, d) || added[rule.Printer] {
+			continue
+		}
+		st, ok := a.stations[rule.Printer]
+		if !ok {
+			a.logger.Warn("print rule targets an unavailable printer, using the default", "printer", rule.Printer)
+			continue
+		}
+		added[rule.Printer] = true
+		targets = append(targets, st)
+	}
+
+	if len(targets) == 0 {
+		if st, ok := a.stations[a.cfg.DefaultPrinter]; ok {
+			targets = append(targets, st)
+		}
+	}
+
+	return targets
+}
+
+func (a *Astro) ruleMatches(rule config.PrintRule, d *Donation) bool {
+	if rule.Currency != "" && !strings.EqualFold(rule.Currency, d.Currency) {
+		return false
+	}
+	if rule.MinAmount > 0 {
+		amount, ok := a.baseAmount(d)
+		if !ok {
+			amount = d.Amount
+		}
+		if amount < rule.MinAmount {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package streamelements
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/DaniruKun/tipfax/internal/fax"
+)
+
+// TipStatus is the payment state of a tip.
+type TipStatus string
+
+const (
+	TipStatusCompleted  TipStatus = "completed" // also sent as "success"
+	TipStatusApproved   TipStatus = "approved"
+	TipStatusPending    TipStatus = "pending"
+	TipStatusRefunded   TipStatus = "refunded"
+	TipStatusChargeback TipStatus = "chargeback"
+	TipStatusMissing    TipStatus = "missing" // the tip event had no status
+	TipStatusUnknown    TipStatus = "unknown"
+)
+
+// parseTipStatus maps a status string from Astro to a TipStatus. An empty
+// status is TipStatusMissing. Unrecognized statuses are TipStatusUnknown and
+// logged to logger.
+func parseTipStatus(s string, logger *slog.Logger) TipStatus {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "success", "completed":
+		return TipStatusCompleted
+	case "approved":
+		return TipStatusApproved
+	case "pending":
+		return TipStatusPending
+	case "refunded", "refund":
+		return TipStatusRefunded
+	case "chargeback", "charged_back":
+		return TipStatusChargeback
+	case "", "missing":
+		return TipStatusMissing
+	case "unknown":
+		return TipStatusUnknown
+	default:
+		logger.Warn("unknown tip status", "status", s)
+		return TipStatusUnknown
+	}
+}
+
+// Reversed reports whether the money for the tip was given back.
+func (s TipStatus) Reversed() bool {
+	return s == TipStatusRefunded || s == TipStatusChargeback
+}
+
+// statusPrintable reports whether tips with status s may be printed.
+func (a *Astro) statusPrintable(s TipStatus) bool {
+	return slices.ContainsFunc(a.cfg.PrintableStatuses, func(p string) bool {
+		return parseTipStatus(p, a.logger) == s
+	})
+}
+
+// handleReversal warns about a refunded or charged back tip and, if it was
+// already printed, optionally prints a notice so the cash drawer can be
+// reconciled.
+func (a *Astro) handleReversal(d *Donation) {
+	if !a.printed.Has(d.TipID) {
+		a.logger.Info("tip reversed", "tip_id", d.TipID, "status", d.Status, "username", d.Username)
+		return
+	}
+
+	a.logger.Error("PRINTED TIP REVERSED: reconcile the cash drawer", "tip_id", d.TipID, "status", d.Status,
+		"username", d.Username, "amount", d.Amount, "currency", d.Currency)
+
+	if !a.cfg.PrintRefundNotices {
+		return
+	}
+	st, ok := a.stations[a.cfg.DefaultPrinter]
+	if !ok {
+		return
+	}
+	notice := strings.ToUpper(string(d.Status)) + ": tip #" + a.sanitizeLine(d.TipID)
+	job := a.newReceiptJob([]string{notice})
+	err := st.do(func(p fax.Printer) error {
+		return a.renderer().render(p, job)
+	})
+	if err != nil {
+		a.logger.Error("failed to print reversal notice", "tip_id", d.TipID, "error", err)
+	}
+}
@@ -0,0 +1,81 @@
+package streamelements
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/DaniruKun/tipfax/internal/config"
+)
+
+func TestParseTipStatus(t *testing.T) {
+	tests := []struct {
+		in   string
+		want TipStatus
+	}{
+		{"success", TipStatusCompleted},
+		{"Completed", TipStatusCompleted},
+		{"approved", TipStatusApproved},
+		{" pending ", TipStatusPending},
+		{"refund", TipStatusRefunded},
+		{"charged_back", TipStatusChargeback},
+		{"", TipStatusMissing},
+		{"missing", TipStatusMissing},
+		{"unknown", TipStatusUnknown},
+		{"settled", TipStatusUnknown},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		if got := parseTipStatus(tt.in, logger); got != tt.want {
+			t.Errorf("parseTipStatus(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestStatusPrinted sends tips with each kind of status through
+// handleTipMessage with the default PRINTABLE_STATUSES, or the one given.
+func TestStatusPrinted(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    string // `"status"` field of the tip event, omitted if empty
+		printable []string
+		want      bool
+	}{
+		{"completed", "success", nil, true},
+		{"approved", "approved", nil, true},
+		{"no status", "", nil, true},
+		{"no status, not printable", "", []string{"completed"}, false},
+		{"unrecognized", "settled", nil, false},
+		{"unrecognized, printable", "settled", []string{"unknown"}, true},
+		{"pending", "pending", nil, false},
+		{"refunded", "refunded", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &recordingPrinter{}
+			a := newTestAstroPrinter(t, p, func(cfg *config.Config) {
+				if tt.printable != nil {
+					cfg.PrintableStatuses = tt.printable
+				}
+			})
+
+			ev := map[string]any{
+				"_id":      "t1",
+				"provider": "paypal",
+				"donation": map[string]any{"user": map[string]any{"username": "Alice"}, "amount": 5, "currency": "USD"},
+			}
+			if tt.status != "" {
+				ev["status"] = tt.status
+			}
+			data, _ := json.Marshal(ev)
+			if err := a.handleTipMessage(Message{Type: "message", Topic: TipsTopic, Data: data}); err != nil {
+				t.Fatalf("handleTipMessage: %v", err)
+			}
+
+			if got := p.Printed(); got != tt.want {
+				t.Errorf("printed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package streamelements
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DaniruKun/tipfax/internal/fax"
+)
+
+// SessionStats summarizes the tips received since the last summary.
+type SessionStats struct {
+	Since       time.Time          `json:"since"`
+	Tips        int                `json:"tips"`
+	Totals      map[string]float64 `json:"totals"` // amount per currency
+	TopDonor    string             `json:"topDonor,omitempty"`
+	TopDonorSum float64            `json:"topDonorAmount,omitempty"` // in the base currency where rates allow
+}
+
+// sessionStats accumulates tip totals for the summary receipt.
+type sessionStats struct {
+	mu     sync.Mutex
+	since  time.Time
+	tips   int
+	totals map[string]float64
+	donors map[string]*donorStats // by donorKey
+}
+
+// donorStats is one donor's tips in the session.
+type donorStats struct {
+	name  string  // as first seen, for display
+	tips  int     // number of tips
+	total float64 // in the base currency where rates allow
+}
+
+// donorKey identifies a donor across tips, ignoring case. It returns "" for
+// anonymous tips, which aren't tracked per donor.
+func donorKey(username string) string {
+	key := strings.ToLower(strings.TrimSpace(username))
+	switch key {
+	case "unknown", "anonymous":
+		return ""
+	}
+	return key
+}
+
+func newSessionStats() *sessionStats {
+	s := &sessionStats{}
+	s.clear()
+	return s
+}
+
+// record adds d, worth baseAmount for ranking donors, to the totals. It
+// returns the donor's tip count and total in the session so far, including
+// d, or zeros for an anonymous tip.
+func (s *sessionStats) record(d *Donation, baseAmount float64) (tips int, total float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tips++
+	s.totals[d.Currency] += d.Amount
+
+	key := donorKey(d.Username)
+	if key == "" {
+		return 0, 0
+	}
+	donor, ok := s.donors[key]
+	if !ok {
+		donor = &donorStats{name: d.Username}
+		s.donors[key] = donor
+	}
+	donor.tips++
+	donor.total += baseAmount
+	return donor.tips, donor.total
+}
+
+func (s *sessionStats) snapshot() SessionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.summarize()
+}
+
+// snapshotAndReset returns the totals so far and starts a new session in one
+// step, so a tip recorded meanwhile counts towards one session or the other.
+// The returned session can be given to restore if its summary isn't printed.
+func (s *sessionStats) snapshotAndReset() (SessionStats, *sessionStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.summarize()
+	old := &sessionStats{since: s.since, tips: s.tips, totals: s.totals, donors: s.donors}
+	s.clear()
+	return stats, old
+}
+
+// restore merges old, taken by snapshotAndReset, back into the session, as
+// if it had never been reset.
+func (s *sessionStats) restore(old *sessionStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.since = old.since
+	s.tips += old.tips
+	for currency, amount := range old.totals {
+		s.totals[currency] += amount
+	}
+	for key, donor := range old.donors {
+		if cur, ok := s.donors[key]; ok {
+			donor.tips += cur.tips
+			donor.total += cur.total
+		}
+		s.donors[key] = donor
+	}
+}
+
+// summarize returns the session's totals. s.mu must be held.
+func (s *sessionStats) summarize() SessionStats {
+	stats := SessionStats{Since: s.since, Tips: s.tips, Totals: maps.Clone(s.totals)}
+	for _, donor := range s.donors {
+		if donor.total > stats.TopDonorSum || (donor.total == stats.TopDonorSum && donor.name < stats.TopDonor) {
+			stats.TopDonor, stats.TopDonorSum = donor.name, donor.total
+		}
+	}
+	return stats
+}
+
+// clear starts a new, empty session. s.mu must be held.
+func (s *sessionStats) clear() {
+	s.since = time.Now()
+	s.tips = 0
+	s.totals = make(map[string]float64)
+	s.donors = make(map[string]*donorStats)
+}
+
+// recordStats adds d to the session totals and notes on d how many tips its
+// donor has sent this session, for the receipt.
+func (a *Astro) recordStats(d *Donation) {
+	amount, ok := a.baseAmount(d)
+	if !ok {
+		amount = d.Amount
+	}
+	d.donorTips, d.donorTotal = a.stats.record(d, amount)
+}
+
+// Stats returns the tip totals since the last summary.
+func (a *Astro) Stats() SessionStats {
+	return a.stats.snapshot()
+}
+
+// PrintSummary prints the session totals on the default printer and starts a
+// new session.
+func (a *Astro) PrintSummary() error {
+	st, ok := a.stations[a.cfg.DefaultPrinter]
+	if !ok {
+		return classify(ErrPrinter, errors.New("no default printer"))
+	}
+
+	stats, old := a.stats.snapshotAndReset()
+	if err := a.printSummary(st, stats); err != nil {
+		a.stats.restore(old)
+		return err
+	}
+
+	a.logger.Info("printed tip summary", "tips", stats.Tips, "since", stats.Since)
+	return nil
+}
+
+func (a *Astro) printSummary(st *station, stats SessionStats) error {
+	lines := []string{
+		a.sanitize(a.label("summary")),
+		stats.Since.Local().Format("2006-01-02 15:04") + " - " + time.Now().Format("2006-01-02 15:04"),
+		a.sanitize(fmt.Sprintf("%s: %d", a.label("tips"), stats.Tips)),
+	}
+	for _, currency := range slices.Sorted(maps.Keys(stats.Totals)) {
+		lines = append(lines, a.sanitize(a.label("total")+": "+a.formatAmount(stats.Totals[currency], currency)))
+	}
+	if stats.TopDonor != "" {
+		lines = append(lines, a.sanitize(a.label("top_donor"))+": "+a.sanitizeLine(stats.TopDonor))
+	}
+
+	job := a.newReceiptJob(lines)
+	return classify(ErrPrinter, st.do(func(p fax.Printer) error {
+		return a.renderer().render(p, job)
+	}))
+}
+
+// RunSummarySchedule prints a summary every day at cfg.SummaryTime, local
+// time, until ctx is cancelled. It returns immediately if no time is set.
+func (a *Astro) RunSummarySchedule(ctx context.Context) {
+	if a.cfg.SummaryTime == "" {
+		return
+	}
+	at, err := time.Parse("15:04", a.cfg.SummaryTime)
+	if err != nil {
+		a.logger.Warn("invalid SUMMARY_TIME, scheduled summaries disabled", "error", err)
+		return
+	}
+
+	for {
+		now := time.Now()
+		next := time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, now.Location())
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		if err := a.PrintSummary(); err != nil {
+			a.logger.Error("failed to print tip summary", "error", err)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package streamelements
+
+import (
+	"sync"
+	"testing"
+)
+
+// tipDuringPrint records a tip on a the first time it's written to, as if
+// one came in while the summary was printing.
+type tipDuringPrint struct {
+	recordingPrinter
+	a    *Astro
+	once sync.Once
+}
+
+func (p *tipDuringPrint) Write(data string) (int, error) {
+	p.once.Do(func() { p.a.recordStats(testDonation("t3")) })
+	return p.recordingPrinter.Write(data)
+}
+
+func TestPrintSummaryKeepsTips(t *testing.T) {
+	t.Run("tip during print", func(t *testing.T) {
+		p := &tipDuringPrint{}
+		a := newTestAstroPrinter(t, p, nil)
+		p.a = a
+		a.recordStats(testDonation("t1"))
+
+		if err := a.PrintSummary(); err != nil {
+			t.Fatalf("PrintSummary: %v", err)
+		}
+		if stats := a.Stats(); stats.Tips != 1 || stats.Totals["USD"] != 5 {
+			t.Errorf("new session has %d tips totalling %v, want the 1 tip sent while printing", stats.Tips, stats.Totals)
+		}
+	})
+
+	t.Run("print fails", func(t *testing.T) {
+		a := newTestAstroPrinter(t, &failingPrinter{}, nil)
+		a.recordStats(testDonation("t1"))
+		since := a.Stats().Since
+		a.recordStats(testDonation("t2"))
+
+		if err := a.PrintSummary(); err == nil {
+			t.Fatal("PrintSummary succeeded on a failing printer")
+		}
+
+		stats := a.Stats()
+		if stats.Tips != 2 || stats.Totals["USD"] != 10 {
+			t.Errorf("session has %d tips totalling %v after a failed summary, want 2 totalling 10 USD", stats.Tips, stats.Totals)
+		}
+		if stats.TopDonor != "Alice" || stats.TopDonorSum != 10 {
+			t.Errorf("top donor %q with %v, want Alice with 10", stats.TopDonor, stats.TopDonorSum)
+		}
+		if !stats.Since.Equal(since) {
+			t.Errorf("session starts at %v, want %v", stats.Since, since)
+		}
+	})
+}
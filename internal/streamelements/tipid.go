@@ -0,0 +1,98 @@
+package streamelements
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// flexID accepts a tip ID encoded as a JSON string or number, or as a
+// MongoDB extended JSON object such as {"$oid": "..."}.
+type flexID string
+
+func (f *flexID) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*f = flexID(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err == nil {
+		*f = flexID(n.String())
+		return nil
+	}
+
+	var oid struct {
+		OID string `json:"$oid"`
+	}
+	if err := json.Unmarshal(b, &oid); err != nil {
+		return err
+	}
+	*f = flexID(oid.OID)
+	return nil
+}
+
+// canonicalTipID normalizes the ID of a tip as sent on either topic, so a
+// moderation event can be matched with its tip. Moderation events have been
+// seen with the ID prefixed, e.g. "tip:5f...", and in upper case.
+func canonicalTipID(id string) string {
+	id = strings.ToLower(strings.TrimSpace(id))
+	for _, prefix := range []string{"tip:", "tip_", "tips/", "tip-"} {
+		id = strings.TrimPrefix(id, prefix)
+	}
+	return id
+}
+
+// firstID returns the first non-empty ID in ids, canonicalized.
+func firstID(ids ...flexID) string {
+	for _, id := range ids {
+		if id != "" {
+			return canonicalTipID(string(id))
+		}
+	}
+	return ""
+}
+
+// seenTip reports whether the tip with id was received recently.
+func (a *Astro) seenTip(id string) bool {
+	return a.received.Has(id)
+}
+
+// earlyDecision is a moderation decision that arrived before its tip.
+type earlyDecision struct {
+	action  ModerationAction
+	expires time.Time
+}
+
+// holdDecision remembers a moderation decision for a tip that hasn't been
+// received yet, for up to PendingTipTTL.
+func (a *Astro) holdDecision(id string, action ModerationAction) {
+	now := time.Now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for id, e := range a.early {
+		if now.After(e.expires) {
+			delete(a.early, id)
+		}
+	}
+	a.early[id] = earlyDecision{action: action, expires: now.Add(a.cfg.PendingTipTTL)}
+}
+
+// takeDecision removes and returns a buffered moderation decision for id.
+func (a *Astro) takeDecision(id string) (ModerationAction, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	e, ok := a.early[id]
+	if !ok {
+		return "", false
+	}
+	delete(a.early, id)
+	if time.Now().After(e.expires) {
+		return "", false
+	}
+	return e.action, true
+}
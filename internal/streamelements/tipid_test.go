@@ -0,0 +1,105 @@
+package streamelements
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/DaniruKun/tipfax/internal/config"
+)
+
+func TestCanonicalTipID(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"5f1a2b3c", "5f1a2b3c"},
+		{"5F1A2B3C", "5f1a2b3c"},
+		{" 5f1a2b3c\n", "5f1a2b3c"},
+		{"tip:5f1a2b3c", "5f1a2b3c"},
+		{"TIP_5f1a2b3c", "5f1a2b3c"},
+		{"tips/5f1a2b3c", "5f1a2b3c"},
+		{"tip-5f1a2b3c", "5f1a2b3c"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := canonicalTipID(tt.in); got != tt.want {
+			t.Errorf("canonicalTipID(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// tipJSON returns a channel.tips payload for a tip with the given raw JSON
+// _id and status.
+func tipJSON(id, status string) json.RawMessage {
+	return json.RawMessage(`{"_id":` + id + `,"provider":"paypal","status":"` + status + `",` +
+		`"donation":{"user":{"username":"Alice"},"amount":5,"currency":"USD"}}`)
+}
+
+// TestModerationMatchesTip checks that a moderation event finds its tip when
+// the two topics encode the ID differently.
+func TestModerationMatchesTip(t *testing.T) {
+	tests := []struct {
+		name       string
+		tipID      string // raw JSON _id of the tip
+		moderation string
+	}{
+		{"same", `"5f1a2b3c"`, `{"tipId":"5f1a2b3c","action":"approved"}`},
+		{"prefixed upper case", `"5f1a2b3c"`, `{"tipId":"TIP:5F1A2B3C","action":"approved"}`},
+		{"donationId", `"5f1a2b3c"`, `{"donationId":"5f1a2b3c","status":"approved"}`},
+		{"nested tip", `"5f1a2b3c"`, `{"tip":{"_id":"5f1a2b3c"},"approved":"approved"}`},
+		{"extended JSON tip", `{"$oid":"5f1a2b3c"}`, `{"tipId":"5f1a2b3c","action":"approved"}`},
+		{"extended JSON moderation", `"5f1a2b3c"`, `{"_id":{"$oid":"5f1a2b3c"},"action":"approved"}`},
+		{"number and string", `12345`, `{"tipId":"12345","action":"approved"}`},
+		{"string and number", `"12345"`, `{"donationId":12345,"action":"approved"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ParseDonation(tipJSON(tt.tipID, "success"), nil)
+			if err != nil {
+				t.Fatalf("ParseDonation: %v", err)
+			}
+			ev, err := ParseModeration(json.RawMessage(tt.moderation))
+			if err != nil {
+				t.Fatalf("ParseModeration: %v", err)
+			}
+			if d.TipID != ev.TipID {
+				t.Errorf("tip ID %q doesn't match moderation tip ID %q", d.TipID, ev.TipID)
+			}
+		})
+	}
+}
+
+// TestEarlyModerationDecision sends the moderation decision before the tip
+// it is about, as Astro sometimes delivers them.
+func TestEarlyModerationDecision(t *testing.T) {
+	tests := []struct {
+		name        string
+		tipID       string
+		moderation  string
+		wantPrinted bool
+	}{
+		{"approved", `"5f1a2b3c"`, `{"tipId":"5f1a2b3c","action":"approved"}`, true},
+		{"approved with other ID format", `{"$oid":"5f1a2b3c"}`, `{"tipId":"tip:5F1A2B3C","action":"approved"}`, true},
+		{"denied", `"5f1a2b3c"`, `{"tipId":"5f1a2b3c","action":"denied"}`, false},
+		{"for another tip", `"5f1a2b3c"`, `{"tipId":"99999999","action":"approved"}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &recordingPrinter{}
+			a := newTestAstroPrinter(t, p, func(cfg *config.Config) { cfg.PrintOnlyApproved = true })
+
+			mod := Message{Type: "message", Topic: TipsModerationTopic, Data: json.RawMessage(tt.moderation)}
+			if err := a.handleModerationMessage(mod); err != nil {
+				t.Fatalf("handleModerationMessage: %v", err)
+			}
+			// Moderated tips arrive pending; the decision decides.
+			tip := Message{Type: "message", Topic: TipsTopic, Data: tipJSON(tt.tipID, "pending")}
+			if err := a.handleTipMessage(tip); err != nil {
+				t.Fatalf("handleTipMessage: %v", err)
+			}
+
+			if got := p.Printed(); got != tt.wantPrinted {
+				t.Errorf("printed = %v, want %v", got, tt.wantPrinted)
+			}
+		})
+	}
+}
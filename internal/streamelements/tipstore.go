@@ -0,0 +1,277 @@
+package streamelements
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ErrTipStoreUnavailable is returned by OpenTipStore in builds without the
+// sqlite build tag.
+var ErrTipStoreUnavailable = errors.New("tip store not available: build with -tags sqlite")
+
+const (
+	tipStoreQueueSize = 256             // writes buffered before new ones are dropped
+	tipStoreBatchSize = 100             // most writes per transaction
+	tipStoreFlushWait = 1 * time.Second // how long a partial batch waits
+)
+
+// tipStoreMigrations are applied in order by OpenTipStore. The database's
+// user_version is the number already applied, so only append to this list.
+var tipStoreMigrations = []string{
+	`CREATE TABLE tips (
+		tip_id     TEXT PRIMARY KEY,
+		username   TEXT NOT NULL,
+		amount     REAL NOT NULL,
+		currency   TEXT NOT NULL,
+		message    TEXT NOT NULL,
+		status     TEXT NOT NULL,
+		moderation TEXT NOT NULL,
+		provider   TEXT NOT NULL,
+		created_at INTEGER NOT NULL, -- Unix milliseconds
+		updated_at INTEGER NOT NULL
+	);
+	CREATE INDEX tips_created_at ON tips (created_at);`,
+	`ALTER TABLE tips ADD COLUMN channel TEXT NOT NULL DEFAULT '';`,
+}
+
+// TipStore keeps every tip in a SQLite database so past tips can be
+// queried. Writes are queued and committed in batches by a background
+// goroutine, so saving never waits for the disk.
+type TipStore struct {
+	db     *sql.DB
+	writes chan tipWrite
+	done   chan struct{}
+	logger *slog.Logger
+}
+
+// tipWrite is a queued change: a whole tip, or a moderation decision for
+// the tip with ID tipID.
+type tipWrite struct {
+	donation   *Donation
+	tipID      string
+	moderation ModerationAction
+}
+
+// OpenTipStore opens or creates the database at path and brings its schema up
+// to date. A nil logger means slog.Default().
+func OpenTipStore(path string, logger *slog.Logger) (*TipStore, error) {
+	if sqliteDriver == "" {
+		return nil, ErrTipStoreUnavailable
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	db, err := sql.Open(sqliteDriver, path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows one writer; a single connection also keeps the
+	// migration and the writer on the same database handle.
+	db.SetMaxOpenConns(1)
+
+	if err := migrateTipStore(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate %s: %w", path, err)
+	}
+
+	s := &TipStore{
+		db:     db,
+		writes: make(chan tipWrite, tipStoreQueueSize),
+		done:   make(chan struct{}),
+		logger: logger,
+	}
+	go s.run()
+	return s, nil
+}
+
+func migrateTipStore(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < len(tipStoreMigrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(tipStoreMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		// PRAGMA doesn't take parameters.
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save queues d to be inserted, or updated if a tip with its ID is stored.
+// If the queue is full the write is dropped and logged.
+func (s *TipStore) Save(d *Donation) {
+	cp := *d
+	s.queue(tipWrite{donation: &cp})
+}
+
+// SetModeration queues a moderation decision for the stored tip tipID.
+func (s *TipStore) SetModeration(tipID string, action ModerationAction) {
+	s.queue(tipWrite{tipID: tipID, moderation: action})
+}
+
+func (s *TipStore) queue(w tipWrite) {
+	select {
+	case s.writes <- w:
+	default:
+		s.logger.Warn("tip store queue full, dropping write", "tip_id", w.key())
+	}
+}
+
+func (w tipWrite) key() string {
+	if w.donation == nil {
+		return w.tipID
+	}
+	if w.donation.TipID != "" {
+		return w.donation.TipID
+	}
+	// Tips without an ID still get a stable key, so a replay updates them
+	// instead of adding a copy.
+	return dedupKey(w.donation)
+}
+
+// run commits queued writes in batches until the queue is closed.
+func (s *TipStore) run() {
+	defer close(s.done)
+
+	var batch []tipWrite
+	timer := time.NewTimer(tipStoreFlushWait)
+	timer.Stop()
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.commit(batch); err != nil {
+			s.logger.Error("failed to store tips", "count", len(batch), "error", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case w, ok := <-s.writes:
+			if !ok {
+				flush()
+				return
+			}
+			if len(batch) == 0 {
+				timer.Reset(tipStoreFlushWait)
+			}
+			batch = append(batch, w)
+			if len(batch) >= tipStoreBatchSize {
+				timer.Stop()
+				flush()
+			}
+		case <-timer.C:
+			flush()
+		}
+	}
+}
+
+func (s *TipStore) commit(batch []tipWrite) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UnixMilli()
+	for _, w := range batch {
+		if w.donation == nil {
+			_, err = tx.Exec(`UPDATE tips SET moderation = ?, updated_at = ? WHERE tip_id = ?`,
+				string(w.moderation), now, w.tipID)
+		} else {
+			d := w.donation
+			_, err = tx.Exec(`INSERT INTO tips
+				(tip_id, username, amount, currency, message, status, moderation, provider, channel, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT (tip_id) DO UPDATE SET
+					status = excluded.status,
+					moderation = CASE WHEN excluded.moderation = '' THEN tips.moderation ELSE excluded.moderation END,
+					updated_at = excluded.updated_at`,
+				w.key(), d.Username, d.Amount, d.Currency, d.Message, string(d.Status),
+				string(d.Moderation), d.Provider, d.Channel, d.Timestamp.UnixMilli(), now)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// TipsSince returns the stored tips created at or after t, oldest first.
+// Writes still queued are not included.
+func (s *TipStore) TipsSince(t time.Time) ([]Donation, error) {
+	rows, err := s.db.Query(`SELECT tip_id, username, amount, currency, message, status, moderation, provider, channel, created_at
+		FROM tips WHERE created_at >= ? ORDER BY created_at`, t.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tips []Donation
+	for rows.Next() {
+		var d Donation
+		var status, moderation string
+		var createdAt int64
+		if err := rows.Scan(&d.TipID, &d.Username, &d.Amount, &d.Currency, &d.Message,
+			&status, &moderation, &d.Provider, &d.Channel, &createdAt); err != nil {
+			return nil, err
+		}
+		d.Status = TipStatus(status)
+		d.Moderation = ModerationAction(moderation)
+		d.Timestamp = time.UnixMilli(createdAt)
+		tips = append(tips, d)
+	}
+	return tips, rows.Err()
+}
+
+// TotalsByCurrency returns the sum of all stored tips per currency, leaving
+// out refunded, charged back and denied tips.
+func (s *TipStore) TotalsByCurrency() (map[string]float64, error) {
+	rows, err := s.db.Query(`SELECT currency, SUM(amount) FROM tips
+		WHERE status NOT IN (?, ?) AND moderation != ?
+		GROUP BY currency`,
+		string(TipStatusRefunded), string(TipStatusChargeback), string(ModerationDenied))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[string]float64)
+	for rows.Next() {
+		var currency string
+		var total float64
+		if err := rows.Scan(&currency, &total); err != nil {
+			return nil, err
+		}
+		totals[currency] = total
+	}
+	return totals, rows.Err()
+}
+
+// Close commits the queued writes and closes the database. Save must not be
+// called afterwards.
+func (s *TipStore) Close() error {
+	close(s.writes)
+	<-s.done
+	return s.db.Close()
+}
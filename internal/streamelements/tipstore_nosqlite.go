@@ -0,0 +1,7 @@
+//go:build !sqlite
+
+package streamelements
+
+// sqliteDriver is empty without the sqlite build tag, which disables
+// TipStore.
+const sqliteDriver = ""
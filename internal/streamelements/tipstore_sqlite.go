@@ -0,0 +1,7 @@
+//go:build sqlite
+
+package streamelements
+
+import _ "modernc.org/sqlite" // registers the "sqlite" database/sql driver
+
+const sqliteDriver = "sqlite"
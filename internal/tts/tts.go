@@ -0,0 +1,107 @@
+// Package tts announces tips by running a text-to-speech command.
+package tts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// timeout bounds how long one announcement may run.
+	timeout = time.Minute
+
+	// queueSize is how many announcements may wait to be spoken. Each can
+	// take a while, so a burst beyond this is dropped rather than read out
+	// long after the tips came in.
+	queueSize = 20
+)
+
+// Speaker runs a command built from a template for each announcement, one
+// at a time from a background goroutine so announcements don't talk over
+// each other. Each whitespace-separated word of the template is rendered
+// separately and passed as one argument, so no shell is involved and
+// substituted text can't add arguments. Leading dashes are trimmed from
+// substituted words, so a username like "-v" can't pass the command a flag.
+type Speaker struct {
+	args   []arg
+	queue  chan any
+	logger *slog.Logger
+}
+
+// arg is one word of the command template.
+type arg struct {
+	tmpl *template.Template
+	// substituted is set if the word has template actions, so its text
+	// may come from a donor.
+	substituted bool
+}
+
+// New parses command, e.g. `espeak {{.Text}}`, and starts the goroutine that
+// runs it. Failed announcements are logged to logger.
+func New(command string, logger *slog.Logger) (*Speaker, error) {
+	words := strings.Fields(command)
+	if len(words) == 0 {
+		return nil, errors.New("empty TTS command")
+	}
+
+	s := &Speaker{
+		queue:  make(chan any, queueSize),
+		logger: logger,
+	}
+	for i, word := range words {
+		t, err := template.New(fmt.Sprintf("arg%d", i)).Parse(word)
+		if err != nil {
+			return nil, fmt.Errorf("parse TTS command: %w", err)
+		}
+		s.args = append(s.args, arg{tmpl: t, substituted: strings.Contains(word, "{{")})
+	}
+	go s.run()
+	return s, nil
+}
+
+// Announce queues data to be spoken. If the queue is full the announcement
+// is dropped and logged rather than blocking.
+func (s *Speaker) Announce(data any) {
+	select {
+	case s.queue <- data:
+	default:
+		s.logger.Warn("TTS queue full, dropping announcement")
+	}
+}
+
+func (s *Speaker) run() {
+	for data := range s.queue {
+		if err := s.Speak(data); err != nil {
+			s.logger.Warn("failed to announce tip", "error", err)
+		}
+	}
+}
+
+// Speak renders the command with data and runs it, waiting for it to
+// finish. Announce should be used instead where announcements may overlap.
+func (s *Speaker) Speak(data any) error {
+	args := make([]string, len(s.args))
+	for i, a := range s.args {
+		var b strings.Builder
+		if err := a.tmpl.Execute(&b, data); err != nil {
+			return fmt.Errorf("render TTS command: %w", err)
+		}
+		args[i] = b.String()
+		if a.substituted {
+			args[i] = strings.TrimLeft(args[i], "-")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/DaniruKun/tipfax/internal/streamelements"
+)
+
+// HealthResponse is the JSON body returned by the health endpoints.
+type HealthResponse struct {
+	State string `json:"status"`
+	streamelements.Status
+}
+
+// HealthHandler reports 200 while the Astro connection is open and a frame was
+// received within maxSilence, and 503 otherwise.
+func HealthHandler(astro *streamelements.Astro, maxSilence time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := astro.Status()
+		healthy := status.Connected && time.Since(status.LastMessageAt) <= maxSilence
+		writeHealth(w, healthy, status)
+	}
+}
+
+// ReadyHandler reports 200 once the tip subscription has been acknowledged.
+func ReadyHandler(astro *streamelements.Astro) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := astro.Status()
+		writeHealth(w, status.Subscribed, status)
+	}
+}
+
+// PauseHandler pauses printing, or resumes it if pause is false, on POST and
+// reports the resulting status like /healthz.
+func PauseHandler(astro *streamelements.Astro, pause bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		astro.SetPaused(pause)
+		writeHealth(w, true, astro.Status())
+	}
+}
+
+func writeHealth(w http.ResponseWriter, ok bool, status streamelements.Status) {
+	resp := HealthResponse{State: "ok", Status: status}
+	code := http.StatusOK
+	if !ok {
+		resp.State = "unavailable"
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// StatsHandler reports the tip totals since the last summary, e.g. for
+// overlays, and the counts of frames read from Astro.
+func StatsHandler(astro *streamelements.Astro) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			streamelements.SessionStats
+			Frames streamelements.FrameStats `json:"frames"`
+		}{astro.Stats(), astro.FrameStats()})
+	}
+}
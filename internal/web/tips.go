@@ -0,0 +1,108 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DaniruKun/tipfax/internal/streamelements"
+)
+
+// sseKeepalive is how often an idle tip stream sends a comment so proxies
+// don't close it.
+const sseKeepalive = 15 * time.Second
+
+// TipStreamHandler streams every handled tip as a Server-Sent Event named
+// "tip" whose data is the tip as JSON, e.g. for browser overlays. Any number
+// of clients can be connected.
+func TipStreamHandler(astro *streamelements.Astro, allowOrigin string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming not supported", http.StatusInternalServerError)
+			return
+		}
+
+		events, cancel := astro.SubscribeEvents()
+		defer cancel()
+
+		setCORS(w, allowOrigin)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepalive := time.NewTicker(sseKeepalive)
+		defer keepalive.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepalive.C:
+				fmt.Fprint(w, ": keepalive\n\n")
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(ev.Donation)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "event: tip\ndata: %s\n\n", data)
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// RecentTipsHandler returns the last n handled tips as a JSON array, newest
+// first. n is taken from the query string and defaults to 20.
+func RecentTipsHandler(astro *streamelements.Astro, allowOrigin string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n := 20
+		if v := r.URL.Query().Get("n"); v != "" {
+			parsed, err := strconv.Atoi(v)
+			if err != nil || parsed < 0 {
+				http.Error(w, "n must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			n = parsed
+		}
+
+		// n may be far more than RecentTips remembers, so size the slice by
+		// what it returned.
+		recent := astro.RecentTips(n)
+		tips := make([]*streamelements.Donation, 0, len(recent))
+		for _, ev := range recent {
+			tips = append(tips, ev.Donation)
+		}
+
+		setCORS(w, allowOrigin)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tips)
+	}
+}
+
+// TipTotalsHandler reports the tip totals since the last summary, like
+// StatsHandler, with CORS headers for browser overlays.
+func TipTotalsHandler(astro *streamelements.Astro, allowOrigin string) http.HandlerFunc {
+	stats := StatsHandler(astro)
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORS(w, allowOrigin)
+		stats(w, r)
+	}
+}
+
+// setCORS lets pages from allowOrigin read the response. An empty
+// allowOrigin sends no CORS headers.
+func setCORS(w http.ResponseWriter, allowOrigin string) {
+	if allowOrigin == "" {
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+	w.Header().Set("Access-Control-Allow-Methods", "GET")
+}
@@ -0,0 +1,96 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DaniruKun/tipfax/internal/config"
+	"github.com/DaniruKun/tipfax/internal/fax"
+	"github.com/DaniruKun/tipfax/internal/streamelements"
+)
+
+// newTestAstro returns an Astro printing to nowhere whose recent tips hold
+// the given number of tips, replayed from a tip log.
+func newTestAstro(t *testing.T, tips int) *streamelements.Astro {
+	t.Helper()
+
+	cfg, err := config.Defaults()
+	if err != nil {
+		t.Fatalf("config.Defaults: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	astro := streamelements.NewAstro(cfg, fax.NewConsolePrinter(io.Discard), logger)
+
+	path := filepath.Join(t.TempDir(), "tips.jsonl")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range tips {
+		d := &streamelements.Donation{
+			TipID:     fmt.Sprintf("tip%d", i),
+			Username:  "Alice",
+			Amount:    5,
+			Currency:  "USD",
+			Status:    streamelements.TipStatusCompleted,
+			Provider:  "paypal",
+			Timestamp: time.Now(),
+		}
+		line, _ := json.Marshal(streamelements.NewTipEvent(streamelements.TipsTopic, d, time.Now()))
+		fmt.Fprintf(f, "%s\n", line)
+	}
+	f.Close()
+
+	if err := astro.Replay(context.Background(), path, streamelements.ReplayOptions{}); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	return astro
+}
+
+func TestRecentTipsHandler(t *testing.T) {
+	astro := newTestAstro(t, 3)
+	handler := RecentTipsHandler(astro, "")
+
+	tests := []struct {
+		query    string
+		wantCode int
+		wantTips int
+	}{
+		{"", http.StatusOK, 3},
+		{"?n=2", http.StatusOK, 2},
+		{"?n=0", http.StatusOK, 0},
+		{"?n=1000000000", http.StatusOK, 3},
+		{"?n=9223372036854775807", http.StatusOK, 3},
+		{"?n=-1", http.StatusBadRequest, 0},
+		{"?n=abc", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(http.MethodGet, "/tips/recent"+tt.query, nil))
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var tips []*streamelements.Donation
+			if err := json.Unmarshal(rec.Body.Bytes(), &tips); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(tips) != tt.wantTips {
+				t.Errorf("got %d tips, want %d", len(tips), tt.wantTips)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// outbox persists undelivered payloads as one file each in a directory, so
+// they survive a restart. Files are named so that sorting them by name gives
+// the order they were added in.
+type outbox struct {
+	dir string
+
+	mu  sync.Mutex
+	seq int
+}
+
+// outboxEntry is the content of one outbox file.
+type outboxEntry struct {
+	Key  string          `json:"key"`
+	Body json.RawMessage `json:"body"`
+}
+
+func openOutbox(dir string) (*outbox, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &outbox{dir: dir}, nil
+}
+
+// add writes a new entry. The file is written under a temporary name and
+// renamed, so a crash never leaves a partial entry behind.
+func (o *outbox) add(key string, body []byte) error {
+	data, err := json.Marshal(outboxEntry{Key: key, Body: body})
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	o.seq++
+	name := fmt.Sprintf("%020d-%06d.json", time.Now().UnixNano(), o.seq)
+	o.mu.Unlock()
+
+	tmp := filepath.Join(o.dir, "."+name+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(o.dir, name))
+}
+
+// pending lists the names of the entries not yet delivered, oldest first.
+func (o *outbox) pending() ([]string, error) {
+	entries, err := os.ReadDir(o.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && !strings.HasPrefix(e.Name(), ".") && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, e.Name())
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+func (o *outbox) read(name string) (outboxEntry, error) {
+	var entry outboxEntry
+	data, err := os.ReadFile(filepath.Join(o.dir, name))
+	if err != nil {
+		return entry, err
+	}
+	err = json.Unmarshal(data, &entry)
+	return entry, err
+}
+
+// remove marks an entry as done.
+func (o *outbox) remove(name string) error {
+	return os.Remove(filepath.Join(o.dir, name))
+}
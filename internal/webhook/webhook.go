@@ -0,0 +1,214 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the request body, keyed with
+// the configured secret, so receivers can verify a payload came from tipfax.
+const SignatureHeader = "X-Tipfax-Signature"
+
+// IdempotencyKeyHeader carries the key passed to Send, e.g. the tip ID. It is
+// the same on every retry of a payload, so receivers can drop duplicates.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const (
+	queueSize   = 100
+	maxAttempts = 3
+
+	// Retry delays for outbox deliveries, which are retried until they succeed.
+	minBackoff = time.Second
+	maxBackoff = 5 * time.Minute
+)
+
+// Dispatcher POSTs JSON payloads to a webhook URL from a background
+// goroutine, so slow or failing receivers never block the caller.
+//
+// Without an outbox, payloads are held in memory and dropped after a few
+// failed attempts. With one, every payload is written to disk before Send
+// returns and removed only once the receiver accepts it, so deliveries are
+// at-least-once and survive restarts.
+type Dispatcher struct {
+	url    string
+	secret []byte
+	client *http.Client
+	queue  chan payload
+	logger *slog.Logger
+
+	outbox *outbox
+	wake   chan struct{}
+}
+
+// payload is one queued request body and its idempotency key.
+type payload struct {
+	key  string
+	body []byte
+}
+
+// New creates a Dispatcher and starts its delivery goroutine. If outboxDir is
+// set, undelivered payloads are persisted there; payloads left over from a
+// previous run are delivered first. Dropped and failed deliveries are logged
+// to logger with the payload's key, never its body.
+func New(url, secret string, timeout time.Duration, outboxDir string, logger *slog.Logger) (*Dispatcher, error) {
+	d := &Dispatcher{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: timeout},
+		logger: logger,
+	}
+
+	if outboxDir == "" {
+		d.queue = make(chan payload, queueSize)
+		go d.run()
+		return d, nil
+	}
+
+	o, err := openOutbox(outboxDir)
+	if err != nil {
+		return nil, fmt.Errorf("open webhook outbox: %w", err)
+	}
+	d.outbox = o
+	d.wake = make(chan struct{}, 1)
+	go d.runOutbox()
+	return d, nil
+}
+
+// Send queues v for delivery with key in the Idempotency-Key header. Without
+// an outbox, the payload is dropped and logged if the queue is full rather
+// than blocking.
+func (d *Dispatcher) Send(key string, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		d.logger.Warn("failed to encode webhook payload", "tip_id", key, "error", err)
+		return
+	}
+
+	if d.outbox != nil {
+		if err := d.outbox.add(key, body); err != nil {
+			d.logger.Warn("failed to write webhook outbox, dropping payload", "tip_id", key, "error", err)
+			return
+		}
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+		return
+	}
+
+	select {
+	case d.queue <- payload{key: key, body: body}:
+	default:
+		d.logger.Warn("webhook queue full, dropping payload", "tip_id", key)
+	}
+}
+
+func (d *Dispatcher) run() {
+	for p := range d.queue {
+		if err := d.deliver(p); err != nil {
+			d.logger.Warn("webhook delivery failed", "tip_id", p.key, "error", err)
+		}
+	}
+}
+
+// runOutbox delivers outbox entries oldest first. An entry that fails with a
+// retryable error is retried with exponential backoff until it succeeds, so
+// entries are delivered in order.
+func (d *Dispatcher) runOutbox() {
+	backoff := minBackoff
+	for {
+		names, err := d.outbox.pending()
+		if err != nil {
+			d.logger.Warn("failed to read webhook outbox", "error", err)
+		}
+		if len(names) == 0 {
+			<-d.wake
+			continue
+		}
+
+		for _, name := range names {
+			entry, err := d.outbox.read(name)
+			if err != nil {
+				d.logger.Warn("dropping unreadable webhook outbox entry", "entry", name, "error", err)
+				d.outbox.remove(name)
+				continue
+			}
+
+			retry, err := d.post(payload{key: entry.Key, body: entry.Body})
+			if err != nil && retry {
+				d.logger.Warn("webhook delivery failed, retrying", "tip_id", entry.Key, "backoff", backoff, "error", err)
+				time.Sleep(backoff)
+				backoff = min(backoff*2, maxBackoff)
+				break
+			}
+			if err != nil {
+				d.logger.Warn("webhook rejected payload, dropping it", "tip_id", entry.Key, "error", err)
+			}
+			backoff = minBackoff
+			if err := d.outbox.remove(name); err != nil {
+				d.logger.Warn("failed to remove delivered webhook outbox entry", "entry", name, "error", err)
+			}
+		}
+	}
+}
+
+// deliver POSTs p, retrying network errors and 5xx responses.
+func (d *Dispatcher) deliver(p payload) error {
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(time.Duration(attempt-1) * time.Second)
+		}
+
+		var retry bool
+		retry, err = d.post(p)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", maxAttempts, err)
+}
+
+// post sends p once. It reports whether a failure is worth retrying: network
+// errors, 429 and 5xx responses are; other non-2xx responses aren't.
+func (d *Dispatcher) post(p payload) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, d.url, bytes.NewReader(p.body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.key != "" {
+		req.Header.Set(IdempotencyKeyHeader, p.key)
+	}
+	if len(d.secret) > 0 {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(d.secret, p.body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("webhook returned %s", resp.Status)
+	case resp.StatusCode >= 300:
+		return false, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return false, nil
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of body keyed with secret.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}